@@ -0,0 +1,32 @@
+package domain
+
+import "context"
+
+// DefaultAccountID is the account uploads go to when they do not name one.
+const DefaultAccountID = "default"
+
+type Account struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Bank     string `json:"bank,omitempty"`
+	Number   string `json:"number,omitempty"`
+	Currency string `json:"currency"`
+}
+
+func DefaultAccount() Account {
+	return Account{ID: DefaultAccountID, Name: "Default", Currency: DefaultCurrency}
+}
+
+type AccountService interface {
+	CreateAccount(ctx context.Context, account Account) (*Account, error)
+	GetAccount(ctx context.Context, id string) (*Account, error)
+	ListAccounts(ctx context.Context) ([]Account, error)
+}
+
+type AccountRepository interface {
+	// Create returns ErrAccountExists when the ID is taken.
+	Create(ctx context.Context, account Account) error
+	// Get returns ErrNotFound for an unknown ID.
+	Get(ctx context.Context, id string) (*Account, error)
+	List(ctx context.Context) ([]Account, error)
+}
@@ -0,0 +1,49 @@
+package domain
+
+import "errors"
+
+var ErrNotDuplicate = errors.New("transaction is not a flagged duplicate")
+
+// DuplicatePolicy decides what happens to an uploaded transaction that
+// matches one stored by an earlier upload of the same account.
+type DuplicatePolicy string
+
+const (
+	// DuplicateFlag stores the transaction marked as a duplicate. It does
+	// not count towards balances until it is kept.
+	DuplicateFlag DuplicatePolicy = "flag"
+	DuplicateSkip DuplicatePolicy = "skip"
+	// DuplicateKeep stores the transaction without looking for duplicates.
+	DuplicateKeep DuplicatePolicy = "keep"
+)
+
+// DuplicateAction resolves a flagged duplicate.
+type DuplicateAction string
+
+const (
+	// DuplicateMerge removes the duplicate after copying the details the
+	// original lacks, such as its reference, onto the original.
+	DuplicateMerge DuplicateAction = "merge"
+	// DuplicateKeepBoth clears the flag, so both transactions count.
+	DuplicateKeepBoth DuplicateAction = "keep"
+)
+
+// DuplicateMatch records which stored transaction a flagged transaction
+// duplicates and how they matched: "id", "reference" or "fuzzy".
+type DuplicateMatch struct {
+	Of   string `json:"of"`
+	Rule string `json:"rule"`
+}
+
+type DuplicateSummary struct {
+	Policy  DuplicatePolicy `json:"policy"`
+	Skipped int             `json:"skipped"`
+	Flagged int             `json:"flagged"`
+}
+
+// DuplicateReview pairs a flagged duplicate with the transaction it
+// matched, which is nil if that has been deleted since.
+type DuplicateReview struct {
+	Duplicate Transaction  `json:"duplicate"`
+	Original  *Transaction `json:"original"`
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// TransactionFilter selects transactions for listing. Zero fields do not
+// filter.
+type TransactionFilter struct {
+	AccountID string
+	Statuses  []TransactionStatus
+	Type      TransactionType
+	Currency  string
+	// From and To bound the timestamp, both inclusive.
+	From time.Time
+	To   time.Time
+	// FromDate and ToDate (YYYY-MM-DD) bound the calendar date of the
+	// timestamp in its own offset, which is the date shown to the user.
+	FromDate string
+	ToDate   string
+	// MinAmount and MaxAmount bound the amount, both inclusive, whatever
+	// its currency.
+	MinAmount *Money
+	MaxAmount *Money
+	// Query is a case-insensitive substring of the name or description.
+	Query    string
+	UploadID string
+	// ExcludeDuplicates leaves out flagged duplicates awaiting review.
+	ExcludeDuplicates bool
+}
+
+func (f TransactionFilter) Matches(tx Transaction) bool {
+	if f.AccountID != "" && tx.AccountID != f.AccountID {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsStatus(f.Statuses, tx.Status) {
+		return false
+	}
+	if f.Type != TypeUnknown && tx.Type != f.Type {
+		return false
+	}
+	if f.Currency != "" && !strings.EqualFold(tx.Amount.Currency, f.Currency) {
+		return false
+	}
+	if !f.From.IsZero() && tx.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && tx.Timestamp.After(f.To) {
+		return false
+	}
+	if f.FromDate != "" || f.ToDate != "" {
+		date := tx.Timestamp.Format("2006-01-02")
+		if f.FromDate != "" && date < f.FromDate {
+			return false
+		}
+		if f.ToDate != "" && date > f.ToDate {
+			return false
+		}
+	}
+	if f.MinAmount != nil && tx.Amount.Cmp(*f.MinAmount) < 0 {
+		return false
+	}
+	if f.MaxAmount != nil && tx.Amount.Cmp(*f.MaxAmount) > 0 {
+		return false
+	}
+	if f.Query != "" {
+		q := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(tx.Name), q) && !strings.Contains(strings.ToLower(tx.Description), q) {
+			return false
+		}
+	}
+	if f.UploadID != "" && (tx.Source == nil || tx.Source.UploadID != f.UploadID) {
+		return false
+	}
+	if f.ExcludeDuplicates && tx.Duplicate != nil {
+		return false
+	}
+	return true
+}
+
+func containsStatus(statuses []TransactionStatus, status TransactionStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidRange = errors.New("invalid range")
+
+// Interval is the size of the buckets of a balance history.
+type Interval string
+
+const (
+	IntervalDay Interval = "day"
+	// IntervalWeek buckets start on Monday.
+	IntervalWeek  Interval = "week"
+	IntervalMonth Interval = "month"
+)
+
+// BalanceHistoryQuery selects a balance history. Buckets follow the
+// calendar of Location. Without From and To the range is the first to the
+// last transaction; From alone runs until now and To alone from the first
+// transaction. The bucket holding the end is always included as a whole.
+type BalanceHistoryQuery struct {
+	AccountID string
+	Interval  Interval
+	From      time.Time
+	To        time.Time
+	// FromDate and ToDate (YYYY-MM-DD) are midnight in Location and take
+	// precedence over From and To.
+	FromDate string
+	ToDate   string
+	Location *time.Location
+}
+
+// BalanceBucket covers [Start, End). Credits and Debits are the successful
+// transactions in it; empty buckets carry the balance forward.
+type BalanceBucket struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Opening Money     `json:"opening_balance"`
+	Closing Money     `json:"closing_balance"`
+	Credits Money     `json:"credits"`
+	Debits  Money     `json:"debits"`
+}
+
+type CurrencyHistory struct {
+	Currency string          `json:"currency"`
+	Buckets  []BalanceBucket `json:"buckets"`
+}
+
+type BalanceHistory struct {
+	AccountID string   `json:"account_id,omitempty"`
+	Interval  Interval `json:"interval"`
+	Timezone  string   `json:"timezone"`
+	// Currencies lists the currencies with a transaction before the end of
+	// the range.
+	Currencies []CurrencyHistory `json:"currencies"`
+}
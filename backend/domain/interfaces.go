@@ -2,16 +2,74 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"io"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAccountExists = errors.New("account already exists")
 )
 
 type TransactionService interface {
-	ProcessUpload(ctx context.Context, fileReader io.Reader) error
-	GetBalance(ctx context.Context) (*BalanceResponse, error)
-	GetIssues(ctx context.Context, params PaginationParams) (*IssuesResponse, error)
+	ProcessUpload(ctx context.Context, fileReader io.Reader, opts UploadOptions) (*UploadResponse, error)
+	// GetBalance and GetIssues cover every account when accountID is empty.
+	// GetBalance only counts transactions up to at unless it is zero.
+	GetBalance(ctx context.Context, accountID string, at time.Time) (*BalanceResponse, error)
+	GetBalanceHistory(ctx context.Context, query BalanceHistoryQuery) (*BalanceHistory, error)
+	// GetOverdrafts lists the periods the balance spent below threshold,
+	// compared regardless of currency.
+	GetOverdrafts(ctx context.Context, accountID string, threshold Money) (*OverdraftReport, error)
+	GetIssues(ctx context.Context, filter IssueFilter, params PaginationParams) (*IssuesResponse, error)
+	// GetIssue returns ErrNotFound unless the ID is a current issue or one
+	// that has a record.
+	GetIssue(ctx context.Context, id string) (*IssueRecord, error)
+	UpdateIssue(ctx context.Context, id string, action IssueAction, update IssueUpdate) (*IssueRecord, error)
+	GetTransaction(ctx context.Context, id string) (*Transaction, error)
+	ListTransactions(ctx context.Context, filter TransactionFilter, params PaginationParams) (*TransactionListResponse, error)
+	// ListUploads covers every account when accountID is empty.
+	ListUploads(ctx context.Context, accountID string) ([]Upload, error)
+	DeleteUpload(ctx context.Context, id string) error
+	// ListDuplicates covers every account when accountID is empty.
+	ListDuplicates(ctx context.Context, accountID string) ([]DuplicateReview, error)
+	ResolveDuplicate(ctx context.Context, id string, action DuplicateAction) (*Transaction, error)
 }
 
+// TransactionRepository keeps every upload as a separate batch. Reads
+// return the union of all batches in upload order.
 type TransactionRepository interface {
-	Store(ctx context.Context, transactions []Transaction) error
+	// Store adds a complete upload at once.
+	Store(ctx context.Context, upload Upload, transactions []Transaction) (*Upload, error)
 	GetAll(ctx context.Context) ([]Transaction, error)
+	// GetByID returns ErrNotFound when no stored transaction has the ID.
+	// When several uploads hold the ID, the latest one wins.
+	GetByID(ctx context.Context, id string) (*Transaction, error)
+	// Begin starts storing an upload that becomes visible once committed.
+	Begin(ctx context.Context, upload Upload) (TransactionWriter, error)
+	ListUploads(ctx context.Context) ([]Upload, error)
+	// DeleteUpload removes an upload with its transactions and reverts the
+	// status changes it made to earlier transactions; it returns ErrNotFound
+	// for an unknown ID.
+	DeleteUpload(ctx context.Context, id string) error
+	// UpdateTransaction replaces the stored transaction with the same ID
+	// and DeleteTransaction removes it; both return ErrNotFound for an
+	// unknown ID.
+	UpdateTransaction(ctx context.Context, tx Transaction) error
+	DeleteTransaction(ctx context.Context, id string) error
+}
+
+// TransactionWriter receives an upload in batches. Nothing is visible to
+// readers until Commit, which returns the stored upload with its version
+// and row count; Rollback discards the batches written so far. Write must
+// not keep the slice it is given.
+type TransactionWriter interface {
+	Write(ctx context.Context, transactions []Transaction) error
+	// Update replaces a transaction of an earlier upload on Commit. The
+	// replaced transaction counts as a row of the new upload, and the status
+	// changes tagged with its ID are undone when it is deleted. Updates of
+	// transactions deleted in the meantime are dropped.
+	Update(ctx context.Context, tx Transaction) error
+	Commit(ctx context.Context) (*Upload, error)
+	Rollback(ctx context.Context) error
 }
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrInvalidTransition = errors.New("invalid issue state transition")
+
+// IssueCodeOverdraft is the rule code of overdraft periods.
+const IssueCodeOverdraft = "OVERDRAFT"
+
+// IssueSeverity ranks how urgent an issue is.
+type IssueSeverity string
+
+const (
+	SeverityLow      IssueSeverity = "low"
+	SeverityMedium   IssueSeverity = "medium"
+	SeverityHigh     IssueSeverity = "high"
+	SeverityCritical IssueSeverity = "critical"
+)
+
+// IssueMatch names an issue rule a transaction matched.
+type IssueMatch struct {
+	Code     string        `json:"code"`
+	Severity IssueSeverity `json:"severity"`
+}
+
+// IssueFilter selects the issues listed by GetIssues. With Rules set only
+// issues matching one of those rule codes are listed. Without States,
+// resolved issues are left out.
+type IssueFilter struct {
+	AccountID string
+	Rules     []string
+	States    []IssueState
+}
+
+type IssueState string
+
+const (
+	IssueOpen         IssueState = "open"
+	IssueAcknowledged IssueState = "acknowledged"
+	IssueResolved     IssueState = "resolved"
+)
+
+type IssueKind string
+
+const (
+	IssueKindTransaction IssueKind = "transaction"
+	IssueKindOverdraft   IssueKind = "overdraft"
+)
+
+// IssueAction is a step in the workflow of an issue.
+type IssueAction string
+
+const (
+	IssueAcknowledge IssueAction = "acknowledge"
+	IssueResolve     IssueAction = "resolve"
+	IssueReopen      IssueAction = "reopen"
+	IssueAssign      IssueAction = "assign"
+	IssueComment     IssueAction = "comment"
+)
+
+// IssueUpdate says who takes an action. Assignee is read by IssueAssign,
+// where an empty one unassigns the issue. A comment may go with any action
+// and is required by IssueComment.
+type IssueUpdate struct {
+	Actor    string `json:"actor"`
+	Assignee string `json:"assignee,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type IssueNote struct {
+	Author string    `json:"author"`
+	Text   string    `json:"text"`
+	At     time.Time `json:"at"`
+}
+
+// IssueEvent is one entry of the audit trail of an issue.
+type IssueEvent struct {
+	Action   IssueAction `json:"action"`
+	Actor    string      `json:"actor"`
+	At       time.Time   `json:"at"`
+	From     IssueState  `json:"from"`
+	To       IssueState  `json:"to"`
+	Assignee string      `json:"assignee,omitempty"`
+}
+
+// IssueRecord tracks the work on an issue. Its ID is the ID of the
+// transaction or overdraft period, which stays the same when the row is
+// uploaded again. Issues nobody has worked on yet have no stored record
+// and are open.
+type IssueRecord struct {
+	ID        string       `json:"id"`
+	Kind      IssueKind    `json:"kind"`
+	AccountID string       `json:"account_id"`
+	State     IssueState   `json:"state"`
+	Assignee  string       `json:"assignee,omitempty"`
+	Comments  []IssueNote  `json:"comments"`
+	History   []IssueEvent `json:"history"`
+}
+
+type IssueRepository interface {
+	// Get returns ErrNotFound for an issue without a record.
+	Get(ctx context.Context, id string) (*IssueRecord, error)
+	List(ctx context.Context) ([]IssueRecord, error)
+	// Update applies fn to the stored record with the ID of record, or to
+	// record itself when there is none, and stores the result unless fn
+	// returns an error. Updates of a record are applied one at a time, so
+	// fn always sees the latest state.
+	Update(ctx context.Context, record IssueRecord, fn func(*IssueRecord) error) (*IssueRecord, error)
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+const DefaultCurrency = "IDR"
+
+// currencyExponents lists the ISO 4217 currencies whose minor unit is not
+// the usual two decimal places.
+var currencyExponents = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Money is an amount in minor units of its currency, e.g. 12345 with
+// exponent 2 is 123.45.
+type Money struct {
+	Minor    int64  `json:"minor"`
+	Currency string `json:"currency"`
+	Exponent int    `json:"exponent"`
+}
+
+func NewMoney(minor int64, currency string) Money {
+	currency = strings.ToUpper(currency)
+	return Money{Minor: minor, Currency: currency, Exponent: CurrencyExponent(currency)}
+}
+
+func (m Money) Neg() Money {
+	m.Minor = -m.Minor
+	return m
+}
+
+func (m Money) Abs() Money {
+	if m.Minor < 0 {
+		m.Minor = -m.Minor
+	}
+	return m
+}
+
+// Cmp compares the values of two amounts regardless of their exponents. It
+// does not look at the currency.
+func (m Money) Cmp(other Money) int {
+	a, b := m.Minor, other.Minor
+	for exp := m.Exponent; exp < other.Exponent; exp++ {
+		a *= 10
+	}
+	for exp := other.Exponent; exp < m.Exponent; exp++ {
+		b *= 10
+	}
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// String formats the amount as a plain decimal, e.g. "-1234.50".
+func (m Money) String() string {
+	digits := strconv.FormatInt(m.Minor, 10)
+	sign := ""
+	if m.Minor < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if m.Exponent <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= m.Exponent {
+		digits = strings.Repeat("0", m.Exponent-len(digits)+1) + digits
+	}
+	cut := len(digits) - m.Exponent
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	type plain Money
+	return json.Marshal(struct {
+		plain
+		Value string `json:"value"`
+	}{plain(m), m.String()})
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// OverdraftPeriod is a stretch of time in which the balance of an account
+// in one currency stayed below a threshold, zero by default.
+type OverdraftPeriod struct {
+	// ID is derived from the transaction that started the period, so it is
+	// stable across uploads.
+	ID        string    `json:"id"`
+	AccountID string    `json:"account_id"`
+	Currency  string    `json:"currency"`
+	Start     time.Time `json:"start"`
+	// End is when the balance got back to the threshold; it is nil while
+	// the period is ongoing.
+	End      *time.Time `json:"end"`
+	Lowest   Money      `json:"lowest_balance"`
+	LowestAt time.Time  `json:"lowest_at"`
+	// Duration runs until now for an ongoing period.
+	Duration int64 `json:"duration_seconds"`
+	// StartedBy and EndedBy are the transactions that crossed the threshold.
+	StartedBy string `json:"started_by"`
+	EndedBy   string `json:"ended_by,omitempty"`
+	// Issue is the issue record, set on issue listings only.
+	Issue *IssueRecord `json:"issue,omitempty"`
+}
+
+type OverdraftReport struct {
+	AccountID string            `json:"account_id,omitempty"`
+	Threshold Money             `json:"threshold"`
+	Periods   []OverdraftPeriod `json:"periods"`
+}
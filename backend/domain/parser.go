@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// SourceInfo is what is known about an uploaded file before it is parsed.
+type SourceInfo struct {
+	Filename string
+	MIMEType string
+	Head     []byte
+}
+
+// Extension returns the lower-cased file extension, e.g. ".csv".
+func (s SourceInfo) Extension() string {
+	return strings.ToLower(path.Ext(s.Filename))
+}
+
+type ParseOptions struct {
+	Lenient     bool
+	Currency    string
+	Location    *time.Location
+	TimeLayouts []string
+	Delimiter   rune
+}
+
+type ParseResult struct {
+	Statements []Statement
+	Report     *ParseReport
+	// Delimiter is the field separator used by delimited text formats.
+	Delimiter rune
+}
+
+// EmitFunc receives each parsed transaction as soon as it is read, with the
+// line it was read from. A *RowError rejects just that row, like a parse
+// error would; any other error stops the parser and is returned from Parse.
+type EmitFunc func(tx Transaction, line int) error
+
+// StatementParser reads one statement file format. Detect reports whether
+// the parser can handle a file, judging by its content, name or MIME type.
+type StatementParser interface {
+	Format() StatementFormat
+	Detect(src SourceInfo) bool
+	Parse(r io.Reader, opts ParseOptions, emit EmitFunc) (*ParseResult, error)
+}
@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+type StatementBalance struct {
+	Amount Money     `json:"amount"`
+	Date   time.Time `json:"date"`
+}
+
+// Statement holds the account level metadata some formats (camt.053,
+// MT940) carry next to their entries.
+type Statement struct {
+	ID                     string            `json:"id,omitempty"`
+	Account                string            `json:"account,omitempty"`
+	Currency               string            `json:"currency,omitempty"`
+	OpeningBalance         *StatementBalance `json:"opening_balance,omitempty"`
+	ClosingBalance         *StatementBalance `json:"closing_balance,omitempty"`
+	ComputedClosingBalance *Money            `json:"computed_closing_balance,omitempty"`
+	BalanceMatches         *bool             `json:"balance_matches,omitempty"`
+
+	// movements is the net amount of the recorded entries per currency.
+	movements map[string]int64
+}
+
+// Record adds a successful entry to the movement CheckBalance applies to
+// the opening balance.
+func (s *Statement) Record(tx Transaction) {
+	if tx.Status != StatusSuccess {
+		return
+	}
+	if s.movements == nil {
+		s.movements = make(map[string]int64)
+	}
+	switch tx.Type {
+	case TypeCredit:
+		s.movements[tx.Amount.Currency] += tx.Amount.Minor
+	case TypeDebit:
+		s.movements[tx.Amount.Currency] -= tx.Amount.Minor
+	}
+}
+
+// CheckBalance applies the recorded entries of the statement to the
+// opening balance and compares the result with the reported closing
+// balance. It does nothing when either balance is missing.
+func (s *Statement) CheckBalance() {
+	if s.OpeningBalance == nil || s.ClosingBalance == nil {
+		return
+	}
+
+	computed := s.OpeningBalance.Amount
+	computed.Minor += s.movements[computed.Currency]
+
+	matches := computed.Cmp(s.ClosingBalance.Amount) == 0
+	s.ComputedClosingBalance = &computed
+	s.BalanceMatches = &matches
+}
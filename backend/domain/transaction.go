@@ -19,16 +19,91 @@ const (
 )
 
 type Transaction struct {
+	// ID is derived from the bank reference or the content of the row, so
+	// the same row gets the same ID in every upload; see pkg/txid.
+	ID          string            `json:"id"`
+	AccountID   string            `json:"account_id"`
 	Timestamp   time.Time         `json:"timestamp"`
 	Name        string            `json:"name"`
 	Type        TransactionType   `json:"type"`
-	Amount      int64             `json:"amount"`
+	Amount      Money             `json:"amount"`
 	Status      TransactionStatus `json:"status"`
 	Description string            `json:"description"`
+	Reference   string            `json:"reference,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	// Tags are added by validation rules configured to tag instead of reject.
+	Tags []string `json:"tags,omitempty"`
+	// Source is where the transaction was read from.
+	Source *TransactionSource `json:"source,omitempty"`
+	// Duplicate is set on a transaction flagged as a duplicate that has not
+	// been reviewed yet.
+	Duplicate *DuplicateMatch `json:"duplicate,omitempty"`
+	// StatusHistory lists every status the transaction had, oldest first.
+	StatusHistory []StatusChange `json:"status_history,omitempty"`
+	// RunningBalance is the account balance in the transaction's currency
+	// right after it, set on listings only.
+	RunningBalance *Money `json:"running_balance,omitempty"`
+	// Issues lists the issue rules the transaction matched, set on issue
+	// listings only.
+	Issues []IssueMatch `json:"issues,omitempty"`
+	// Issue is the issue record, set on issue listings only.
+	Issue *IssueRecord `json:"issue,omitempty"`
 }
 
+// StatusChange records the status a transaction had from the given time,
+// and the upload that reported it.
+type StatusChange struct {
+	Status   TransactionStatus `json:"status"`
+	At       time.Time         `json:"at"`
+	UploadID string            `json:"upload_id,omitempty"`
+	// TransactionID and Timestamp are those of the row that settled the
+	// transaction, so uploading that row again is caught as a duplicate.
+	TransactionID string    `json:"transaction_id,omitempty"`
+	Timestamp     time.Time `json:"timestamp,omitzero"`
+}
+
+type TransactionSource struct {
+	UploadID string `json:"upload_id"`
+	// File is the statement file, or the entry of an uploaded archive.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line"`
+}
+
+// CurrencyBalance is the balance of one currency with the totals it is
+// made of. Credits, Debits and Failed are positive amounts.
+type CurrencyBalance struct {
+	Currency string         `json:"currency"`
+	Balance  Money          `json:"balance"`
+	Credits  Money          `json:"credits"`
+	Debits   Money          `json:"debits"`
+	Pending  PendingBalance `json:"pending"`
+	// Projected is the balance once every pending transaction settles.
+	Projected Money                     `json:"projected_balance"`
+	Failed    Money                     `json:"failed"`
+	Counts    map[TransactionStatus]int `json:"counts"`
+}
+
+// PendingBalance is the exposure of the transactions still pending.
+type PendingBalance struct {
+	Credits Money `json:"credits"`
+	Debits  Money `json:"debits"`
+	Net     Money `json:"net"`
+}
+
+type AccountBalance struct {
+	AccountID string            `json:"account_id"`
+	Balances  []CurrencyBalance `json:"balances"`
+}
+
+// BalanceResponse holds one balance per currency; amounts in different
+// currencies are never added together. The consolidated view across all
+// accounts also lists the balances of each account.
 type BalanceResponse struct {
-	TotalBalance int64 `json:"total_balance"`
+	AccountID string `json:"account_id,omitempty"`
+	// At is the cut-off the balance was taken at, if any.
+	At       *time.Time        `json:"at,omitempty"`
+	Balances []CurrencyBalance `json:"balances"`
+	Accounts []AccountBalance  `json:"accounts,omitempty"`
 }
 
 type PaginationParams struct {
@@ -45,7 +120,15 @@ type PaginationMetadata struct {
 	TotalPages  int `json:"total_pages"`
 }
 
+// IssuesResponse pages the transactions that need attention. The
+// overdraft periods of the same accounts are listed next to them in full.
 type IssuesResponse struct {
+	Transactions []Transaction      `json:"transactions"`
+	Overdrafts   []OverdraftPeriod  `json:"overdrafts"`
+	Metadata     PaginationMetadata `json:"metadata"`
+}
+
+type TransactionListResponse struct {
 	Transactions []Transaction      `json:"transactions"`
 	Metadata     PaginationMetadata `json:"metadata"`
 }
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+type UploadMode string
+
+const (
+	ModeStrict  UploadMode = "strict"
+	ModeLenient UploadMode = "lenient"
+)
+
+type StatementFormat string
+
+const (
+	FormatCSV   StatementFormat = "csv"
+	FormatOFX   StatementFormat = "ofx"
+	FormatCAMT  StatementFormat = "camt053"
+	FormatMT940 StatementFormat = "mt940"
+)
+
+type UploadOptions struct {
+	// AccountID is the account the upload is added to; empty means
+	// DefaultAccountID.
+	AccountID string
+	Mode      UploadMode
+	Filename  string
+	MIMEType  string
+	// Format forces a registered parser instead of detecting one.
+	Format StatementFormat
+	// Currency applies to amounts whose currency the file does not state.
+	Currency string
+	// Location applies to timestamps without an offset; nil means the
+	// service default.
+	Location *time.Location
+	// TimeLayouts forces the timestamp formats instead of detecting them.
+	TimeLayouts []string
+	// Encoding and Delimiter override the sniffed values when set.
+	Encoding  string
+	Delimiter rune
+	// Duplicates defaults to DuplicateFlag.
+	Duplicates DuplicatePolicy
+}
+
+// DetectedSettings reports how an upload was read, whether sniffed or
+// chosen by the client.
+type DetectedSettings struct {
+	Encoding  string `json:"encoding"`
+	BOM       bool   `json:"bom"`
+	Delimiter string `json:"delimiter,omitempty"`
+}
+
+// RowError describes a single rejected line of an uploaded statement.
+type RowError struct {
+	Line   int    `json:"line"`
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+	// Rule names the validation rule that flagged the row, if any.
+	Rule string `json:"rule,omitempty"`
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("invalid %s on line %d: %s", e.Field, e.Line, e.Reason)
+}
+
+type ParseReport struct {
+	TotalRows    int        `json:"total_rows"`
+	AcceptedRows int        `json:"accepted_rows"`
+	RejectedRows int        `json:"rejected_rows"`
+	Errors       []RowError `json:"errors"`
+	// Warnings lists accepted rows that broke a validation rule set to warn.
+	Warnings []RowError `json:"warnings,omitempty"`
+}
+
+// FileResult describes one imported statement file; archives produce one
+// per contained file.
+type FileResult struct {
+	Name       string           `json:"name,omitempty"`
+	Format     StatementFormat  `json:"format"`
+	Detected   DetectedSettings `json:"detected"`
+	Report     *ParseReport     `json:"report"`
+	Statements []Statement      `json:"statements,omitempty"`
+}
+
+// Upload is one stored import. Every upload is kept as its own batch, and
+// reads return the union of all batches.
+type Upload struct {
+	ID string `json:"id"`
+	// Version numbers uploads in the order they were stored.
+	Version   int       `json:"version"`
+	AccountID string    `json:"account_id"`
+	Filename  string    `json:"filename,omitempty"`
+	Rows      int       `json:"rows"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UploadResponse struct {
+	// UploadID is recorded as the source of every imported transaction.
+	UploadID  string       `json:"upload_id"`
+	Version   int          `json:"version"`
+	AccountID string       `json:"account_id"`
+	Mode      UploadMode   `json:"mode"`
+	Archive   string       `json:"archive,omitempty"`
+	Files     []FileResult `json:"files"`
+	// Duplicates counts the transactions that matched earlier uploads.
+	Duplicates DuplicateSummary `json:"duplicates"`
+	// Reconciled counts the pending transactions of earlier uploads that
+	// this upload settled.
+	Reconciled int `json:"reconciled"`
+}
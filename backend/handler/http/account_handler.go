@@ -0,0 +1,95 @@
+// bank-statement-viewer/handler/http/account_handler.go
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+// maxAccountSize bounds the JSON body of an account.
+const maxAccountSize = 16 * 1024
+
+type AccountHandler struct {
+	service domain.AccountService
+}
+
+func NewAccountHandler(s domain.AccountService) *AccountHandler {
+	return &AccountHandler{
+		service: s,
+	}
+}
+
+func (h *AccountHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/accounts", h.Accounts)
+	mux.HandleFunc("/accounts/{id}", h.GetAccount)
+}
+
+// Accounts lists the accounts on GET and creates one on POST.
+func (h *AccountHandler) Accounts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.ListAccounts(w, r)
+	case http.MethodPost:
+		h.CreateAccount(w, r)
+	default:
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (h *AccountHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accounts, err := h.service.ListAccounts(ctx)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Accounts retrieved successfully", accounts)
+}
+
+func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	var account domain.Account
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAccountSize)).Decode(&account); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid account JSON")
+		return
+	}
+
+	ctx := r.Context()
+
+	created, err := h.service.CreateAccount(ctx, account)
+	if errors.Is(err, domain.ErrAccountExists) {
+		RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, "Account created successfully", created)
+}
+
+func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+
+	account, err := h.service.GetAccount(ctx, r.PathValue("id"))
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, "Account not found")
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Account retrieved successfully", account)
+}
@@ -2,17 +2,39 @@
 package http
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/amount"
+	"github.com/novanm/bank-viewer/backend/pkg/archive"
+	"github.com/novanm/bank-viewer/backend/pkg/textenc"
+	"github.com/novanm/bank-viewer/backend/pkg/timeparse"
 )
 
 const maxUploadSize = 20 * 1024 * 1024 // 20 MB
 
+// maxFieldSize bounds the plain form fields read before the file part.
+const maxFieldSize = 1024
+
+// maxIssueUpdateSize bounds the JSON body of an issue update.
+const maxIssueUpdateSize = 16 * 1024
+
+var delimiters = map[string]rune{
+	",": ',', "comma": ',',
+	";": ';', "semicolon": ';',
+	"\t": '\t', "tab": '\t',
+	"|": '|', "pipe": '|',
+}
+
 type TransactionHandler struct {
 	service domain.TransactionService
 }
@@ -32,7 +54,17 @@ func (h *TransactionHandler) RegisterRoutes(mux *http.ServeMux) {
 	})
 
 	mux.HandleFunc("/balance", h.GetBalance)
+	mux.HandleFunc("/balance/history", h.GetBalanceHistory)
 	mux.HandleFunc("/issues", h.GetIssues)
+	mux.HandleFunc("/issues/{id}", h.GetIssue)
+	mux.HandleFunc("/issues/{id}/{action}", h.UpdateIssue)
+	mux.HandleFunc("/analysis/overdrafts", h.GetOverdrafts)
+	mux.HandleFunc("/transactions", h.ListTransactions)
+	mux.HandleFunc("/transactions/{id}", h.GetTransaction)
+	mux.HandleFunc("/uploads", h.ListUploads)
+	mux.HandleFunc("/uploads/{id}", h.DeleteUpload)
+	mux.HandleFunc("/duplicates", h.ListDuplicates)
+	mux.HandleFunc("/duplicates/{id}/{action}", h.ResolveDuplicate)
 }
 
 func (h *TransactionHandler) Upload(w http.ResponseWriter, r *http.Request) {
@@ -40,6 +72,66 @@ func (h *TransactionHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
+
+	opts := domain.UploadOptions{}
+	switch mode := domain.UploadMode(strings.ToLower(r.URL.Query().Get("mode"))); mode {
+	case "", domain.ModeStrict, domain.ModeLenient:
+		opts.Mode = mode
+	default:
+		RespondWithError(w, http.StatusBadRequest, "Invalid mode, expected 'strict' or 'lenient'")
+		return
+	}
+
+	if currency := strings.ToUpper(r.URL.Query().Get("currency")); currency != "" {
+		if len(currency) != 3 {
+			RespondWithError(w, http.StatusBadRequest, "Invalid currency, expected a 3-letter ISO 4217 code")
+			return
+		}
+		opts.Currency = currency
+	}
+
+	if tz := timeValue(r.URL.Query(), "tz"); tz != "" {
+		loc, err := timeparse.LoadLocation(tz)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		opts.Location = loc
+	}
+	opts.TimeLayouts = r.URL.Query()["time_format"]
+
+	if enc := r.URL.Query().Get("encoding"); enc != "" {
+		if _, err := textenc.Lookup(enc); err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		opts.Encoding = enc
+	}
+
+	// A bank profile is picked like any other format.
+	fields := map[string]string{
+		"account_id": r.URL.Query().Get("account_id"),
+		"format":     r.URL.Query().Get("format"),
+		"profile":    r.URL.Query().Get("profile"),
+	}
+
+	switch policy := domain.DuplicatePolicy(strings.ToLower(r.URL.Query().Get("duplicates"))); policy {
+	case "", domain.DuplicateFlag, domain.DuplicateSkip, domain.DuplicateKeep:
+		opts.Duplicates = policy
+	default:
+		RespondWithError(w, http.StatusBadRequest, "Invalid duplicates policy, expected 'flag', 'skip' or 'keep'")
+		return
+	}
+
+	if d := r.URL.Query().Get("delimiter"); d != "" {
+		delimiter, ok := delimiters[strings.ToLower(d)]
+		if !ok {
+			RespondWithError(w, http.StatusBadRequest, "Invalid delimiter, expected comma, semicolon, tab or pipe")
+			return
+		}
+		opts.Delimiter = delimiter
+	}
+
 	reader, err := r.MultipartReader()
 
 	if err != nil {
@@ -52,7 +144,7 @@ func (h *TransactionHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var filePart io.Reader
+	var filePart *multipart.Part
 
 	for {
 		part, err := reader.NextPart()
@@ -66,11 +158,21 @@ func (h *TransactionHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		if part.FormName() == "file" {
 			filePart = part
 			break
-		} else {
-			if _, err := io.Copy(io.Discard, part); err != nil {
+		}
+		// The file is streamed, so fields only take effect when they are
+		// sent before it.
+		if _, ok := fields[part.FormName()]; ok {
+			value, err := io.ReadAll(io.LimitReader(part, maxFieldSize))
+			if err != nil {
 				RespondWithError(w, http.StatusInternalServerError, "Failed to parse multipart form")
 				return
 			}
+			fields[part.FormName()] = string(value)
+			continue
+		}
+		if _, err := io.Copy(io.Discard, part); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Failed to parse multipart form")
+			return
 		}
 	}
 
@@ -79,13 +181,37 @@ func (h *TransactionHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	format := strings.ToLower(strings.TrimSpace(fields["format"]))
+	profile := strings.ToLower(strings.TrimSpace(fields["profile"]))
+	switch {
+	case format == "":
+		format = profile
+	case profile != "" && profile != format:
+		RespondWithError(w, http.StatusBadRequest, "Use either 'format' or 'profile', not both")
+		return
+	}
+	opts.Format = domain.StatementFormat(format)
+	opts.AccountID = strings.TrimSpace(fields["account_id"])
+
+	opts.Filename = filePart.FileName()
+	opts.MIMEType = filePart.Header.Get("Content-Type")
+
 	ctx := r.Context()
-	if err := h.service.ProcessUpload(ctx, filePart); err != nil {
+	result, err := h.service.ProcessUpload(ctx, filePart, opts)
+	if err != nil {
+		if errors.Is(err, archive.ErrTooLarge) {
+			RespondWithError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
 		RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	RespondWithJSON(w, http.StatusOK, "File uploaded successfully", nil)
+	RespondWithJSON(w, http.StatusOK, "File uploaded successfully", result)
 }
 
 func (h *TransactionHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
@@ -95,8 +221,23 @@ func (h *TransactionHandler) GetBalance(w http.ResponseWriter, r *http.Request)
 	}
 
 	ctx := r.Context()
+	q := r.URL.Query()
 
-	balance, err := h.service.GetBalance(ctx)
+	var at time.Time
+	if value := timeValue(q, "at"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "Invalid at, expected an RFC 3339 timestamp")
+			return
+		}
+		at = t
+	}
+
+	balance, err := h.service.GetBalance(ctx, q.Get("account_id"), at)
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -109,14 +250,367 @@ func (h *TransactionHandler) GetBalance(w http.ResponseWriter, r *http.Request)
 	RespondWithJSON(w, http.StatusOK, "Balance retrieved successfully", balance)
 }
 
+func (h *TransactionHandler) GetBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	query := domain.BalanceHistoryQuery{
+		AccountID: q.Get("account_id"),
+		Interval:  domain.Interval(strings.ToLower(q.Get("interval"))),
+	}
+	switch query.Interval {
+	case "":
+		query.Interval = domain.IntervalDay
+	case domain.IntervalDay, domain.IntervalWeek, domain.IntervalMonth:
+	default:
+		RespondWithError(w, http.StatusBadRequest, "Invalid interval, expected day, week or month")
+		return
+	}
+
+	if tz := timeValue(q, "tz"); tz != "" {
+		loc, err := timeparse.LoadLocation(tz)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		query.Location = loc
+	}
+
+	for _, bound := range []struct {
+		name string
+		date *string
+		at   *time.Time
+	}{
+		{"from", &query.FromDate, &query.From},
+		{"to", &query.ToDate, &query.To},
+	} {
+		value := timeValue(q, bound.name)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, value); err == nil {
+			*bound.date = value
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid %s, expected YYYY-MM-DD or an RFC 3339 timestamp", bound.name))
+			return
+		}
+		*bound.at = at
+	}
+
+	history, err := h.service.GetBalanceHistory(ctx, query)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, domain.ErrInvalidRange):
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Balance history retrieved successfully", history)
+}
+
 func (h *TransactionHandler) GetIssues(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
+	params := parsePagination(r.URL.Query())
+
+	ctx := r.Context()
+
+	filter := domain.IssueFilter{AccountID: r.URL.Query().Get("account_id")}
+	// Rule codes may be repeated or comma separated: ?rule=FAILED,WEEKEND.
+	for _, value := range r.URL.Query()["rule"] {
+		for _, code := range strings.Split(value, ",") {
+			if code = strings.TrimSpace(code); code != "" {
+				filter.Rules = append(filter.Rules, code)
+			}
+		}
+	}
+
+	// States may be repeated or comma separated: ?state=open,resolved.
+	for _, value := range r.URL.Query()["state"] {
+		for _, st := range strings.Split(value, ",") {
+			state := domain.IssueState(strings.ToLower(strings.TrimSpace(st)))
+			switch state {
+			case "":
+			case domain.IssueOpen, domain.IssueAcknowledged, domain.IssueResolved:
+				filter.States = append(filter.States, state)
+			default:
+				RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid state %q, expected open, acknowledged or resolved", st))
+				return
+			}
+		}
+	}
+
+	issues, err := h.service.GetIssues(ctx, filter, params)
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Issues retrieved successfully", issues)
+}
+
+func (h *TransactionHandler) GetIssue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+
+	issue, err := h.service.GetIssue(ctx, r.PathValue("id"))
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, "Issue not found")
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Issue retrieved successfully", issue)
+}
+
+// UpdateIssue takes an action on an issue. The JSON body names the actor
+// and carries the assignee or comment.
+func (h *TransactionHandler) UpdateIssue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	action := domain.IssueAction(r.PathValue("action"))
+	switch action {
+	case domain.IssueAcknowledge, domain.IssueResolve, domain.IssueReopen, domain.IssueAssign, domain.IssueComment:
+	default:
+		RespondWithError(w, http.StatusNotFound, "Unknown action, expected 'acknowledge', 'resolve', 'reopen', 'assign' or 'comment'")
+		return
+	}
+
+	var update domain.IssueUpdate
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIssueUpdateSize)).Decode(&update); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid issue update JSON")
+		return
+	}
+
+	ctx := r.Context()
+
+	issue, err := h.service.UpdateIssue(ctx, r.PathValue("id"), action, update)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, "Issue not found")
+		return
+	case errors.Is(err, domain.ErrInvalidTransition):
+		RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Issue updated successfully", issue)
+}
+
+// GetOverdrafts lists the periods the balance spent below zero, or below
+// ?threshold= in major units of each currency.
+func (h *TransactionHandler) GetOverdrafts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	var threshold domain.Money
+	if value := q.Get("threshold"); value != "" {
+		minor, err := amount.Parse(value, filterAmountExponent)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid threshold: %v", err))
+			return
+		}
+		threshold = domain.Money{Minor: minor, Exponent: filterAmountExponent}
+	}
+
+	report, err := h.service.GetOverdrafts(ctx, q.Get("account_id"), threshold)
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Overdrafts retrieved successfully", report)
+}
+
+func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+
+	tx, err := h.service.GetTransaction(ctx, r.PathValue("id"))
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, "Transaction not found")
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Transaction retrieved successfully", tx)
+}
+
+func (h *TransactionHandler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
 	q := r.URL.Query()
 
+	filter, err := parseFilter(q)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params := parsePagination(q)
+
+	ctx := r.Context()
+
+	transactions, err := h.service.ListTransactions(ctx, filter, params)
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Transactions retrieved successfully", transactions)
+}
+
+func (h *TransactionHandler) ListUploads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+
+	uploads, err := h.service.ListUploads(ctx, r.URL.Query().Get("account_id"))
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Uploads retrieved successfully", uploads)
+}
+
+func (h *TransactionHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+
+	err := h.service.DeleteUpload(ctx, r.PathValue("id"))
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, "Upload not found")
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Upload deleted successfully", nil)
+}
+
+func (h *TransactionHandler) ListDuplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+
+	duplicates, err := h.service.ListDuplicates(ctx, r.URL.Query().Get("account_id"))
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Duplicates retrieved successfully", duplicates)
+}
+
+// ResolveDuplicate handles POST /duplicates/{id}/merge and
+// POST /duplicates/{id}/keep.
+func (h *TransactionHandler) ResolveDuplicate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RespondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	action := domain.DuplicateAction(r.PathValue("action"))
+	if action != domain.DuplicateMerge && action != domain.DuplicateKeepBoth {
+		RespondWithError(w, http.StatusNotFound, "Unknown action, expected 'merge' or 'keep'")
+		return
+	}
+
+	ctx := r.Context()
+
+	tx, err := h.service.ResolveDuplicate(ctx, r.PathValue("id"), action)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, domain.ErrNotDuplicate):
+		RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, "Duplicate resolved successfully", tx)
+}
+
+func parsePagination(q url.Values) domain.PaginationParams {
 	page, _ := strconv.Atoi(q.Get("page"))
 	if page <= 0 {
 		page = 1
@@ -141,20 +635,98 @@ func (h *TransactionHandler) GetIssues(w http.ResponseWriter, r *http.Request) {
 		sortDir = "desc"
 	}
 
-	params := domain.PaginationParams{
+	return domain.PaginationParams{
 		Page:    page,
 		Limit:   limit,
 		SortBy:  sortBy,
 		SortDir: sortDir,
 	}
+}
 
-	ctx := r.Context()
+// filterAmountExponent is the precision amount bounds are read at; no
+// currency has more than three decimal places.
+const filterAmountExponent = 3
 
-	issues, err := h.service.GetIssues(ctx, params)
-	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, err.Error())
-		return
+// timeValue returns a timestamp or timezone parameter. A '+' of a UTC
+// offset that was not encoded, as in ?at=2024-06-30T23:59:59+07:00, is
+// decoded as a space and is put back.
+func timeValue(q url.Values, name string) string {
+	return strings.ReplaceAll(q.Get(name), " ", "+")
+}
+
+func parseFilter(q url.Values) (domain.TransactionFilter, error) {
+	filter := domain.TransactionFilter{AccountID: q.Get("account_id")}
+
+	// Statuses may be repeated or comma separated: ?status=PENDING,FAILED.
+	for _, value := range q["status"] {
+		for _, s := range strings.Split(value, ",") {
+			status := domain.TransactionStatus(strings.ToUpper(strings.TrimSpace(s)))
+			switch status {
+			case "":
+			case domain.StatusSuccess, domain.StatusPending, domain.StatusFailed:
+				filter.Statuses = append(filter.Statuses, status)
+			default:
+				return filter, fmt.Errorf("Invalid status %q, expected SUCCESS, PENDING or FAILED", s)
+			}
+		}
 	}
 
-	RespondWithJSON(w, http.StatusOK, "Issues retrieved successfully", issues)
+	switch txType := domain.TransactionType(strings.ToUpper(q.Get("type"))); txType {
+	case domain.TypeUnknown, domain.TypeCredit, domain.TypeDebit:
+		filter.Type = txType
+	default:
+		return filter, fmt.Errorf("Invalid type, expected CREDIT or DEBIT")
+	}
+
+	if currency := strings.ToUpper(q.Get("currency")); currency != "" {
+		if len(currency) != 3 {
+			return filter, fmt.Errorf("Invalid currency, expected a 3-letter ISO 4217 code")
+		}
+		filter.Currency = currency
+	}
+
+	for _, bound := range []struct {
+		name string
+		date *string
+		at   *time.Time
+	}{
+		{"from", &filter.FromDate, &filter.From},
+		{"to", &filter.ToDate, &filter.To},
+	} {
+		value := timeValue(q, bound.name)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, value); err == nil {
+			*bound.date = value
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid %s, expected YYYY-MM-DD or an RFC 3339 timestamp", bound.name)
+		}
+		*bound.at = at
+	}
+
+	for _, bound := range []struct {
+		name string
+		dst  **domain.Money
+	}{
+		{"min_amount", &filter.MinAmount},
+		{"max_amount", &filter.MaxAmount},
+	} {
+		value := q.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		minor, err := amount.Parse(value, filterAmountExponent)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid %s: %v", bound.name, err)
+		}
+		*bound.dst = &domain.Money{Minor: minor, Exponent: filterAmountExponent}
+	}
+
+	filter.Query = strings.TrimSpace(q.Get("q"))
+	filter.UploadID = q.Get("upload_id")
+	return filter, nil
 }
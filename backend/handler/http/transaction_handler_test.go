@@ -0,0 +1,50 @@
+// bank-statement-viewer/handler/http/transaction_handler_test.go
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/repository/memory"
+	"github.com/novanm/bank-viewer/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeParameters_UnencodedOffset(t *testing.T) {
+	s := service.NewTransactionService(memory.NewMemoryRepository())
+	_, err := s.ProcessUpload(context.Background(), strings.NewReader(`timestamp,name,type,amount,status
+2024-06-30T16:00:00Z,SALARY,CREDIT,1000,SUCCESS
+2024-06-30T18:00:00Z,RENT,DEBIT,400,SUCCESS`), domain.UploadOptions{})
+	assert.NoError(t, err)
+
+	mux := http.NewServeMux()
+	NewTransactionHandler(s).RegisterRoutes(mux)
+	get := func(target string) (int, APIResponse) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		var response APIResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+
+	// 23:59:59 in Jakarta is 16:59:59 UTC: the salary is in, the rent not.
+	code, response := get("/balance?at=2024-06-30T23:59:59+07:00")
+	assert.Equal(t, http.StatusOK, code, response.Message)
+	balances := response.Data.(map[string]any)["balances"].([]any)
+	assert.Equal(t, "1000.00", balances[0].(map[string]any)["balance"].(map[string]any)["value"])
+
+	code, response = get("/balance/history?interval=day&tz=+07:00&from=2024-06-30T00:00:00+07:00&to=2024-07-01")
+	assert.Equal(t, http.StatusOK, code, response.Message)
+	assert.Equal(t, "+07:00", response.Data.(map[string]any)["timezone"])
+
+	code, response = get("/transactions?from=2024-07-01T00:00:00+07:00")
+	assert.Equal(t, http.StatusOK, code, response.Message)
+	transactions := response.Data.(map[string]any)["transactions"].([]any)
+	assert.Equal(t, 1, len(transactions))
+	assert.Equal(t, "RENT", transactions[0].(map[string]any)["name"])
+}
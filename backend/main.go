@@ -2,29 +2,109 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"io/fs"
 	"log"
 	"net/http"
+	"os"
+	_ "time/tzdata"
 
 	"github.com/novanm/bank-viewer/backend/domain"
 	httpHandler "github.com/novanm/bank-viewer/backend/handler/http"
+	"github.com/novanm/bank-viewer/backend/pkg/camtparser"
+	"github.com/novanm/bank-viewer/backend/pkg/csvparser"
+	"github.com/novanm/bank-viewer/backend/pkg/issuerule"
+	"github.com/novanm/bank-viewer/backend/pkg/mt940parser"
+	"github.com/novanm/bank-viewer/backend/pkg/ofxparser"
+	"github.com/novanm/bank-viewer/backend/pkg/profile"
+	"github.com/novanm/bank-viewer/backend/pkg/registry"
+	"github.com/novanm/bank-viewer/backend/pkg/timeparse"
+	"github.com/novanm/bank-viewer/backend/pkg/validation"
 	"github.com/novanm/bank-viewer/backend/repository/memory"
 	"github.com/novanm/bank-viewer/backend/service"
 )
 
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
 func main() {
 	var repo domain.TransactionRepository = memory.NewMemoryRepository()
+	var accountRepo domain.AccountRepository = memory.NewAccountRepository()
+	var issueRepo domain.IssueRepository = memory.NewIssueRepository()
+
+	// Uploads that do not name an account go to the default one.
+	if err := accountRepo.Create(context.Background(), domain.DefaultAccount()); err != nil {
+		log.Fatalf("could not create default account: %v", err)
+	}
+
+	location, err := timeparse.LoadLocation(getEnv("STATEMENT_TIMEZONE", "Asia/Jakarta"))
+	if err != nil {
+		log.Fatalf("invalid STATEMENT_TIMEZONE: %v", err)
+	}
+
+	profilesDir := getEnv("PROFILES_DIR", "config/profiles")
+	profiles, err := profile.LoadDir(profilesDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("no bank profiles found in %s", profilesDir)
+	} else if err != nil {
+		log.Fatalf("invalid bank profile: %v", err)
+	}
+
+	// Formats with a recognisable signature go first, then the bank
+	// profiles, which are recognised by their header row; generic CSV has
+	// neither and catches everything else.
+	parsers := registry.New()
+	parsers.Register(ofxparser.New())
+	parsers.Register(camtparser.New())
+	parsers.Register(mt940parser.New())
+	for _, p := range profiles {
+		parsers.Register(p)
+	}
+	parsers.Register(csvparser.New())
+	parsers.SetFallback(csvparser.New())
+
+	validator, err := validation.New(validation.DefaultConfig())
+	if path := os.Getenv("VALIDATION_RULES"); path != "" {
+		validator, err = validation.Load(path)
+	}
+	if err != nil {
+		log.Fatalf("invalid VALIDATION_RULES: %v", err)
+	}
+
+	issueRules := issuerule.Default()
+	if path := os.Getenv("ISSUE_RULES"); path != "" {
+		issueRules, err = issuerule.Load(path)
+	}
+	if err != nil {
+		log.Fatalf("invalid ISSUE_RULES: %v", err)
+	}
 
-	var txService domain.TransactionService = service.NewTransactionService(repo)
+	var txService domain.TransactionService = service.NewTransactionService(repo,
+		service.WithLocation(location),
+		service.WithParsers(parsers),
+		service.WithValidator(validator),
+		service.WithAccounts(accountRepo),
+		service.WithIssueRules(issueRules),
+		service.WithIssueTracking(issueRepo),
+	)
+	var accountService domain.AccountService = service.NewAccountService(accountRepo)
 
 	handler := httpHandler.NewTransactionHandler(txService)
+	accountHandler := httpHandler.NewAccountHandler(accountService)
 
 	mux := http.NewServeMux()
 	handler.RegisterRoutes(mux)
+	accountHandler.RegisterRoutes(mux)
 
 	corsHandler := func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 			if r.Method == http.MethodOptions {
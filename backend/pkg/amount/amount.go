@@ -0,0 +1,129 @@
+package amount
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+// Parse reads a decimal amount such as "1234.56", "1,234.56", "-50" or
+// "(1,234.56)" and returns it in minor units for the given exponent. Commas
+// are treated as thousands separators and a dot as the decimal point; a
+// comma that does not start a group of three digits is an error, so
+// "1234,56" is not misread as 123456.
+func Parse(s string, exponent int) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty amount")
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = !negative
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if strings.Contains(whole, ",") {
+		groups := strings.Split(whole, ",")
+		for i, g := range groups {
+			if len(g) != 3 && (i > 0 || g == "" || len(g) > 3) {
+				return 0, fmt.Errorf("invalid thousands separator in amount %q", s)
+			}
+		}
+		whole = strings.Join(groups, "")
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	trimmed := strings.TrimRight(frac, "0")
+	if len(trimmed) > exponent {
+		return 0, fmt.Errorf("amount %q has more than %d decimal places", s, exponent)
+	}
+	frac = trimmed + strings.Repeat("0", exponent-len(trimmed))
+
+	v, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	if negative {
+		v = -v
+	}
+	return v, nil
+}
+
+// ParseLocale reads an amount written with the given decimal separator.
+// With ',' the dot is the thousands separator, as in the Indonesian
+// "1.234.567,89", and is checked like the comma of Parse; any other
+// separator behaves like Parse.
+func ParseLocale(s string, exponent int, decimal rune) (int64, error) {
+	if decimal != ',' {
+		return Parse(s, exponent)
+	}
+	v, err := Parse(strings.NewReplacer(".", ",", ",", ".").Replace(s), exponent)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q with a decimal comma: %w", strings.TrimSpace(s), err)
+	}
+	return v, nil
+}
+
+// Limit is an upper bound on the size of amounts, as set in rule files.
+type Limit struct {
+	currency string
+	minor    int64
+	exponent int
+}
+
+// ParseLimit parses a limit once: at the precision of currency when set,
+// in which case it only applies to that currency, and otherwise as a whole
+// number that is scaled to the currency of each amount it is checked
+// against.
+func ParseLimit(s, currency string) (Limit, error) {
+	l := Limit{currency: strings.ToUpper(currency)}
+	if l.currency != "" {
+		l.exponent = domain.CurrencyExponent(l.currency)
+	}
+	minor, err := Parse(s, l.exponent)
+	if err != nil {
+		return Limit{}, err
+	}
+	if minor < 0 {
+		return Limit{}, fmt.Errorf("%q is negative", s)
+	}
+	l.minor = minor
+	return l, nil
+}
+
+// Exceeded reports whether m, whatever its sign, is above the limit.
+func (l Limit) Exceeded(m domain.Money) bool {
+	if l.currency != "" && m.Currency != l.currency {
+		return false
+	}
+	scaled := l.minor
+	for i := l.exponent; i < m.Exponent; i++ {
+		scaled *= 10
+	}
+	return m.Abs().Minor > scaled
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+// bank-statement-viewer/pkg/amount/amount_test.go
+package amount
+
+import (
+	"testing"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("1234.56", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123456), v)
+
+	v, err = Parse("1,234.5", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123450), v)
+
+	v, err = Parse("1,234,567.89", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123456789), v)
+
+	v, err = Parse("-250000", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-25000000), v)
+
+	v, err = Parse("(1,000.00)", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1000), v)
+
+	v, err = Parse("+.5", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), v)
+}
+
+func TestParse_Error(t *testing.T) {
+	_, err := Parse("10.505", 2)
+	assert.ErrorContains(t, err, "more than 2 decimal places")
+
+	_, err = Parse("abc", 2)
+	assert.Error(t, err)
+
+	_, err = Parse("", 2)
+	assert.Error(t, err)
+
+	// A comma must start a group of three digits, so decimal commas are
+	// rejected rather than read as thousands.
+	for _, s := range []string{"1234,56", "1,5", "1,2345", ",234", "1234,567", "1,,234"} {
+		_, err := Parse(s, 2)
+		assert.ErrorContains(t, err, "invalid thousands separator", s)
+	}
+	v, err := Parse("1,234.56", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123456), v)
+}
+
+func TestParseLocale(t *testing.T) {
+	v, err := ParseLocale("1.234.567,89", 2, ',')
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123456789), v)
+
+	v, err = ParseLocale("-10,5", 2, ',')
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1050), v)
+
+	v, err = ParseLocale("1,234.50", 2, '.')
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123450), v)
+
+	// A dot-decimal value in a comma profile is not read as thousands.
+	_, err = ParseLocale("1500.00", 2, ',')
+	assert.ErrorContains(t, err, "invalid thousands separator")
+	_, err = ParseLocale("1.5", 2, ',')
+	assert.Error(t, err)
+}
+
+func TestLimit(t *testing.T) {
+	limit, err := ParseLimit("1000", "")
+	assert.NoError(t, err)
+	assert.False(t, limit.Exceeded(domain.NewMoney(100000, "IDR")))
+	assert.True(t, limit.Exceeded(domain.NewMoney(-100001, "IDR")), "the sign is ignored")
+	assert.True(t, limit.Exceeded(domain.NewMoney(1001, "JPY")), "scaled to each currency")
+
+	limit, err = ParseLimit("10.50", "usd")
+	assert.NoError(t, err)
+	assert.True(t, limit.Exceeded(domain.NewMoney(1051, "USD")))
+	assert.False(t, limit.Exceeded(domain.NewMoney(1051, "EUR")), "other currencies are not limited")
+
+	_, err = ParseLimit("-5", "")
+	assert.ErrorContains(t, err, "negative")
+	_, err = ParseLimit("10.505", "USD")
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindNone Kind = ""
+	KindGzip Kind = "gzip"
+	KindZip  Kind = "zip"
+)
+
+// maxZipEntries bounds the number of files read from one zip archive.
+const maxZipEntries = 100
+
+var ErrTooLarge = errors.New("decompressed upload exceeds the size limit")
+
+var (
+	magicGzip     = []byte{0x1f, 0x8b}
+	magicZip      = []byte("PK\x03\x04")
+	magicZipEmpty = []byte("PK\x05\x06")
+)
+
+func Detect(head []byte) Kind {
+	switch {
+	case bytes.HasPrefix(head, magicGzip):
+		return KindGzip
+	case bytes.HasPrefix(head, magicZip), bytes.HasPrefix(head, magicZipEmpty):
+		return KindZip
+	}
+	return KindNone
+}
+
+// Walk calls fn for every statement file in r. Plain files are passed
+// through as they are; a gzip stream yields its single member and a zip
+// archive yields each regular file. At most limit decompressed bytes are
+// read across all files before ErrTooLarge is returned, which guards
+// against zip bombs.
+func Walk(r io.Reader, name string, limit int64, fn func(name string, r io.Reader) error) (Kind, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(4)
+	kind := Detect(head)
+
+	budget := &limitedBudget{remaining: limit}
+	switch kind {
+	case KindGzip:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return kind, fmt.Errorf("invalid gzip upload: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+
+		inner := gz.Name
+		if inner == "" {
+			inner = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".gzip")
+		}
+		return kind, fn(inner, &limitedReader{r: gz, budget: budget})
+	case KindZip:
+		return kind, walkZip(br, budget, fn)
+	}
+	return kind, fn(name, br)
+}
+
+// walkZip needs random access to the central directory, so the compressed
+// archive is buffered in memory; its size is bounded by the upload limit.
+func walkZip(r io.Reader, budget *limitedBudget, fn func(name string, r io.Reader) error) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read zip upload: %w", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("invalid zip upload: %w", err)
+	}
+
+	files := make([]*zip.File, 0, len(zr.File))
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || isHidden(f.Name) {
+			continue
+		}
+		if f.UncompressedSize64 > uint64(budget.remaining) {
+			return fmt.Errorf("%s: %w", f.Name, ErrTooLarge)
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return errors.New("zip upload contains no statement files")
+	}
+	if len(files) > maxZipEntries {
+		return fmt.Errorf("zip upload contains more than %d files", maxZipEntries)
+	}
+
+	for _, f := range files {
+		if err := walkZipFile(f, budget, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkZipFile(f *zip.File, budget *limitedBudget, fn func(name string, r io.Reader) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	br := bufio.NewReader(&limitedReader{r: rc, budget: budget})
+	head, _ := br.Peek(4)
+	if Detect(head) != KindNone {
+		return fmt.Errorf("%s: nested archives are not supported", f.Name)
+	}
+	if err := fn(f.Name, br); err != nil {
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// isHidden skips the metadata entries macOS and others add to archives.
+func isHidden(name string) bool {
+	if strings.HasPrefix(name, "__MACOSX/") {
+		return true
+	}
+	return strings.HasPrefix(path.Base(name), ".")
+}
+
+type limitedBudget struct {
+	remaining int64
+}
+
+// limitedReader fails with ErrTooLarge instead of silently truncating, so a
+// partial file is never mistaken for a complete statement.
+type limitedReader struct {
+	r      io.Reader
+	budget *limitedBudget
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.budget.remaining < 0 {
+		return 0, ErrTooLarge
+	}
+	// Reading one byte past the budget tells a file that ends exactly at
+	// the limit apart from one that goes over it.
+	if int64(len(p)) > l.budget.remaining+1 {
+		p = p[:l.budget.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.budget.remaining -= int64(n)
+	if l.budget.remaining < 0 {
+		return 0, ErrTooLarge
+	}
+	return n, err
+}
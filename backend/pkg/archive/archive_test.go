@@ -0,0 +1,89 @@
+// bank-statement-viewer/pkg/archive/archive_test.go
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func collect(t *testing.T, data []byte, name string, limit int64) (Kind, map[string]string, error) {
+	files := make(map[string]string)
+	kind, err := Walk(bytes.NewReader(data), name, limit, func(name string, r io.Reader) error {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		files[name] = string(content)
+		return nil
+	})
+	return kind, files, err
+}
+
+func TestWalk_PlainFile(t *testing.T) {
+	kind, files, err := collect(t, []byte("a,b,c"), "plain.csv", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, KindNone, kind)
+	assert.Equal(t, map[string]string{"plain.csv": "a,b,c"}, files)
+}
+
+func TestWalk_Gzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte("a,b,c"))
+	_ = gz.Close()
+
+	kind, files, err := collect(t, buf.Bytes(), "june.csv.gz", 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, KindGzip, kind)
+	assert.Equal(t, map[string]string{"june.csv": "a,b,c"}, files)
+}
+
+func TestWalk_GzipBomb(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(strings.Repeat("0", 1<<20)))
+	_ = gz.Close()
+
+	_, _, err := collect(t, buf.Bytes(), "bomb.gz", 1024)
+
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestWalk_Zip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"a.csv": "1", "dir/b.csv": "2", "__MACOSX/._a.csv": "x", ".hidden": "y"} {
+		w, _ := zw.Create(name)
+		_, _ = w.Write([]byte(content))
+	}
+	_, _ = zw.Create("empty-dir/")
+	_ = zw.Close()
+
+	kind, files, err := collect(t, buf.Bytes(), "all.zip", 100)
+
+	assert.NoError(t, err)
+	assert.Equal(t, KindZip, kind)
+	assert.Equal(t, map[string]string{"a.csv": "1", "dir/b.csv": "2"}, files)
+}
+
+func TestWalk_ZipLimitAcrossFiles(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"a.csv", "b.csv"} {
+		w, _ := zw.Create(name)
+		_, _ = w.Write([]byte(strings.Repeat("x", 60)))
+	}
+	_ = zw.Close()
+
+	_, _, err := collect(t, buf.Bytes(), "all.zip", 100)
+
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
@@ -0,0 +1,338 @@
+package camtparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/amount"
+	"github.com/novanm/bank-viewer/backend/pkg/timeparse"
+)
+
+type Options struct {
+	// Lenient keeps the valid entries and reports the rejected ones instead
+	// of failing on the first bad Ntry.
+	Lenient bool
+	// Currency is used for amounts without a Ccy attribute. It defaults to
+	// domain.DefaultCurrency.
+	Currency string
+	// Location is the timezone for dates without an offset. It defaults to
+	// UTC.
+	Location *time.Location
+}
+
+// Sniff reports whether the beginning of a file looks like an ISO 20022
+// camt.053 bank-to-customer statement.
+func Sniff(head []byte) bool {
+	return bytes.Contains(head, []byte("camt.053")) || bytes.Contains(head, []byte("BkToCstmrStmt"))
+}
+
+type amountValue struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type dateValue struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type account struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+}
+
+type balance struct {
+	Code        string      `xml:"Tp>CdOrPrtry>Cd"`
+	Amount      amountValue `xml:"Amt"`
+	CreditDebit string      `xml:"CdtDbtInd"`
+	Date        dateValue   `xml:"Dt"`
+}
+
+// status covers both the plain <Sts>BOOK</Sts> of camt.053.001.02 and the
+// nested <Sts><Cd>BOOK</Cd></Sts> used from version 08 on.
+type status struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+type transactionDetails struct {
+	EndToEndID string   `xml:"Refs>EndToEndId"`
+	Debtor     string   `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPty  string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	Creditor   string   `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPt string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+	Remittance []string `xml:"RmtInf>Ustrd"`
+}
+
+type entry struct {
+	Reference   string               `xml:"NtryRef"`
+	Amount      amountValue          `xml:"Amt"`
+	CreditDebit string               `xml:"CdtDbtInd"`
+	Status      status               `xml:"Sts"`
+	BookingDate dateValue            `xml:"BookgDt"`
+	ValueDate   dateValue            `xml:"ValDt"`
+	ServicerRef string               `xml:"AcctSvcrRef"`
+	Details     []transactionDetails `xml:"NtryDtls>TxDtls"`
+	Additional  string               `xml:"AddtlNtryInf"`
+}
+
+func ParseWithOptions(fileReader io.Reader, opts Options) ([]domain.Transaction, []domain.Statement, *domain.ParseReport, error) {
+	transactions := make([]domain.Transaction, 0)
+	statements, report, err := Stream(fileReader, opts, func(tx domain.Transaction, _ int) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return transactions, statements, report, nil
+}
+
+// Stream reads the Stmt elements of a camt.053 document one entry at a
+// time and passes each imported entry to emit. Only booked (BOOK) and
+// pending (PDNG) entries are imported; informational entries do not move
+// the balance and are skipped.
+func Stream(fileReader io.Reader, opts Options, emit domain.EmitFunc) ([]domain.Statement, *domain.ParseReport, error) {
+	decoder := xml.NewDecoder(fileReader)
+	// Uploads are transcoded to UTF-8 before they reach the parser, so the
+	// encoding named in the XML declaration no longer applies.
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	defaultCurrency := strings.ToUpper(opts.Currency)
+	if defaultCurrency == "" {
+		defaultCurrency = domain.DefaultCurrency
+	}
+
+	statements := make([]domain.Statement, 0)
+	report := &domain.ParseReport{Errors: make([]domain.RowError, 0)}
+
+	var current *domain.Statement
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read camt.053: %w", err)
+		}
+
+		switch el := token.(type) {
+		case xml.StartElement:
+			line, _ := decoder.InputPos()
+			switch {
+			case el.Name.Local == "Stmt":
+				current = &domain.Statement{}
+			case current == nil:
+				continue
+			case el.Name.Local == "Id" && current.ID == "":
+				if err := decoder.DecodeElement(&current.ID, &el); err != nil {
+					return nil, nil, fmt.Errorf("invalid camt.053 on line %d: %w", line, err)
+				}
+			case el.Name.Local == "Acct":
+				var acct account
+				if err := decoder.DecodeElement(&acct, &el); err != nil {
+					return nil, nil, fmt.Errorf("invalid camt.053 on line %d: %w", line, err)
+				}
+				current.Account = acct.IBAN
+				if current.Account == "" {
+					current.Account = acct.Other
+				}
+				current.Currency = acct.Currency
+			case el.Name.Local == "Bal":
+				var bal balance
+				if err := decoder.DecodeElement(&bal, &el); err != nil {
+					return nil, nil, fmt.Errorf("invalid camt.053 on line %d: %w", line, err)
+				}
+				if err := applyBalance(current, bal, defaultCurrency, opts.Location); err != nil {
+					return nil, nil, fmt.Errorf("invalid balance on line %d: %w", line, err)
+				}
+			case el.Name.Local == "Ntry":
+				var ntry entry
+				if err := decoder.DecodeElement(&ntry, &el); err != nil {
+					return nil, nil, fmt.Errorf("invalid camt.053 on line %d: %w", line, err)
+				}
+
+				tx, ok, err := toTransaction(ntry, line, defaultCurrency, opts.Location)
+				if !ok {
+					continue
+				}
+				report.TotalRows++
+				if err == nil {
+					err = emit(tx, line)
+				}
+				if err != nil {
+					var rowErr *domain.RowError
+					if !opts.Lenient || !errors.As(err, &rowErr) {
+						return nil, nil, err
+					}
+					report.RejectedRows++
+					report.Errors = append(report.Errors, *rowErr)
+					continue
+				}
+				report.AcceptedRows++
+				if current.Currency == "" {
+					current.Currency = tx.Amount.Currency
+				}
+				current.Record(tx)
+			}
+		case xml.EndElement:
+			if el.Name.Local == "Stmt" && current != nil {
+				current.CheckBalance()
+				statements = append(statements, *current)
+				current = nil
+			}
+		}
+	}
+
+	return statements, report, nil
+}
+
+func applyBalance(stmt *domain.Statement, bal balance, defaultCurrency string, loc *time.Location) error {
+	code := strings.ToUpper(bal.Code)
+	if code != "OPBD" && code != "PRCD" && code != "CLBD" {
+		return nil
+	}
+
+	value, err := toMoney(bal.Amount, defaultCurrency)
+	if err != nil {
+		return err
+	}
+	date, err := parseDate(bal.Date, loc)
+	if err != nil {
+		return err
+	}
+
+	if strings.ToUpper(bal.CreditDebit) == "DBIT" {
+		value = value.Neg()
+	}
+
+	b := &domain.StatementBalance{Amount: value, Date: date}
+	switch code {
+	case "OPBD":
+		stmt.OpeningBalance = b
+	case "PRCD":
+		if stmt.OpeningBalance == nil {
+			stmt.OpeningBalance = b
+		}
+	case "CLBD":
+		stmt.ClosingBalance = b
+	}
+	return nil
+}
+
+// toTransaction converts an entry; ok is false for entries that are not
+// imported at all.
+func toTransaction(ntry entry, line int, defaultCurrency string, loc *time.Location) (domain.Transaction, bool, error) {
+	var txStatus domain.TransactionStatus
+	code := ntry.Status.Code
+	if code == "" {
+		code = ntry.Status.Value
+	}
+	switch strings.ToUpper(strings.TrimSpace(code)) {
+	case "BOOK":
+		txStatus = domain.StatusSuccess
+	case "PDNG":
+		txStatus = domain.StatusPending
+	default:
+		return domain.Transaction{}, false, nil
+	}
+
+	var txType domain.TransactionType
+	switch strings.ToUpper(ntry.CreditDebit) {
+	case "CRDT":
+		txType = domain.TypeCredit
+	case "DBIT":
+		txType = domain.TypeDebit
+	default:
+		return domain.Transaction{}, true, &domain.RowError{Line: line, Field: "CdtDbtInd", Reason: fmt.Sprintf("unknown indicator %q", ntry.CreditDebit)}
+	}
+
+	value, err := toMoney(ntry.Amount, defaultCurrency)
+	if err != nil {
+		return domain.Transaction{}, true, &domain.RowError{Line: line, Field: "Amt", Reason: err.Error()}
+	}
+
+	date := ntry.BookingDate
+	if date.Date == "" && date.DateTime == "" {
+		date = ntry.ValueDate
+	}
+	timestamp, err := parseDate(date, loc)
+	if err != nil {
+		return domain.Transaction{}, true, &domain.RowError{Line: line, Field: "BookgDt", Reason: err.Error()}
+	}
+
+	var name, endToEnd string
+	var remittance []string
+	for _, d := range ntry.Details {
+		if name == "" {
+			if txType == domain.TypeCredit {
+				name = firstNonEmpty(d.Debtor, d.DebtorPty)
+			} else {
+				name = firstNonEmpty(d.Creditor, d.CreditorPt)
+			}
+		}
+		if endToEnd == "" && d.EndToEndID != "NOTPROVIDED" {
+			endToEnd = d.EndToEndID
+		}
+		remittance = append(remittance, d.Remittance...)
+	}
+
+	description := strings.Join(remittance, " ")
+	if description == "" {
+		description = ntry.Additional
+	}
+
+	var metadata map[string]string
+	if endToEnd != "" {
+		metadata = map[string]string{"end_to_end_id": endToEnd}
+	}
+
+	return domain.Transaction{
+		Timestamp:   timestamp,
+		Name:        name,
+		Type:        txType,
+		Amount:      value,
+		Status:      txStatus,
+		Description: strings.TrimSpace(description),
+		Reference:   firstNonEmpty(ntry.ServicerRef, ntry.Reference),
+		Metadata:    metadata,
+	}, true, nil
+}
+
+func toMoney(a amountValue, defaultCurrency string) (domain.Money, error) {
+	currency := strings.ToUpper(strings.TrimSpace(a.Currency))
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	minor, err := amount.Parse(a.Value, domain.CurrencyExponent(currency))
+	if err != nil {
+		return domain.Money{}, err
+	}
+	return domain.NewMoney(minor, currency), nil
+}
+
+func parseDate(d dateValue, loc *time.Location) (time.Time, error) {
+	if d.DateTime != "" {
+		return timeparse.Parse(d.DateTime, []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"}, loc)
+	}
+	return timeparse.Parse(d.Date, []string{"2006-01-02", "2006-01-02Z07:00"}, loc)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+// bank-statement-viewer/pkg/camtparser/parser_test.go
+package camtparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+const statement = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>MSG-1</MsgId></GrpHdr>
+    <Stmt>
+      <Id>STMT-2024-06</Id>
+      <Acct><Id><IBAN>ID12BANK0001</IBAN></Id><Ccy>IDR</Ccy></Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="IDR">1000000</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+        <Dt><Dt>2024-06-01</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="IDR">1750000</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+        <Dt><Dt>2024-06-30</Dt></Dt>
+      </Bal>
+      <Ntry>
+        <Amt Ccy="IDR">1000000</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2024-06-10</Dt></BookgDt>
+        <AcctSvcrRef>BANK-REF-1</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>E2E-1</EndToEndId></Refs>
+          <RltdPties><Dbtr><Nm>COMPANY A</Nm></Dbtr></RltdPties>
+          <RmtInf><Ustrd>salary June</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="IDR">250000</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><DtTm>2024-06-12T10:15:00+07:00</DtTm></BookgDt>
+        <NtryDtls><TxDtls><RltdPties><Cdtr><Nm>RESTAURANT</Nm></Cdtr></RltdPties></TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="IDR">50000</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+        <BookgDt><Dt>2024-06-29</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="IDR">1</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>INFO</Sts>
+        <BookgDt><Dt>2024-06-29</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+func TestParseWithOptions_Success(t *testing.T) {
+	transactions, statements, report, err := ParseWithOptions(strings.NewReader(statement), Options{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(transactions))
+	assert.Equal(t, 3, report.AcceptedRows)
+
+	assert.Equal(t, domain.TypeCredit, transactions[0].Type)
+	assert.Equal(t, domain.StatusSuccess, transactions[0].Status)
+	assert.Equal(t, "COMPANY A", transactions[0].Name)
+	assert.Equal(t, "salary June", transactions[0].Description)
+	assert.Equal(t, "BANK-REF-1", transactions[0].Reference)
+	assert.Equal(t, "E2E-1", transactions[0].Metadata["end_to_end_id"])
+
+	assert.Equal(t, domain.TypeDebit, transactions[1].Type)
+	assert.Equal(t, "RESTAURANT", transactions[1].Name)
+	assert.Equal(t, domain.StatusPending, transactions[2].Status)
+
+	assert.Equal(t, 1, len(statements))
+	stmt := statements[0]
+	assert.Equal(t, "STMT-2024-06", stmt.ID)
+	assert.Equal(t, "ID12BANK0001", stmt.Account)
+	assert.Equal(t, "IDR", stmt.Currency)
+	assert.Equal(t, "1000000.00", stmt.OpeningBalance.Amount.String())
+	assert.Equal(t, "1750000.00", stmt.ClosingBalance.Amount.String())
+	assert.Equal(t, "1750000.00", stmt.ComputedClosingBalance.String())
+	assert.True(t, *stmt.BalanceMatches)
+}
+
+func TestParseWithOptions_BalanceMismatch(t *testing.T) {
+	data := strings.Replace(statement, "<Amt Ccy=\"IDR\">1750000</Amt>", "<Amt Ccy=\"IDR\">1800000</Amt>", 1)
+
+	_, statements, _, err := ParseWithOptions(strings.NewReader(data), Options{})
+
+	assert.NoError(t, err)
+	assert.False(t, *statements[0].BalanceMatches)
+}
+
+func TestParseWithOptions_LenientReport(t *testing.T) {
+	data := strings.Replace(statement, "<CdtDbtInd>DBIT</CdtDbtInd>\n        <Sts>PDNG</Sts>", "<CdtDbtInd>XXXX</CdtDbtInd>\n        <Sts>PDNG</Sts>", 1)
+
+	_, _, _, err := ParseWithOptions(strings.NewReader(data), Options{})
+	assert.Error(t, err)
+
+	transactions, _, report, err := ParseWithOptions(strings.NewReader(data), Options{Lenient: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(transactions))
+	assert.Equal(t, 1, report.RejectedRows)
+	assert.Equal(t, "CdtDbtInd", report.Errors[0].Field)
+}
+
+func TestSniff(t *testing.T) {
+	assert.True(t, Sniff([]byte(statement)))
+	assert.False(t, Sniff([]byte("<OFX>")))
+}
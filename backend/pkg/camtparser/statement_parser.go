@@ -0,0 +1,36 @@
+package camtparser
+
+import (
+	"io"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+// StatementParser adapts the camt.053 parser to the parser registry. Other
+// XML documents share the camt MIME types and extension, so only the
+// content is trusted.
+type StatementParser struct{}
+
+func New() *StatementParser {
+	return &StatementParser{}
+}
+
+func (p *StatementParser) Format() domain.StatementFormat {
+	return domain.FormatCAMT
+}
+
+func (p *StatementParser) Detect(src domain.SourceInfo) bool {
+	return Sniff(src.Head)
+}
+
+func (p *StatementParser) Parse(r io.Reader, opts domain.ParseOptions, emit domain.EmitFunc) (*domain.ParseResult, error) {
+	statements, report, err := Stream(r, Options{
+		Lenient:  opts.Lenient,
+		Currency: opts.Currency,
+		Location: opts.Location,
+	}, emit)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ParseResult{Statements: statements, Report: report}, nil
+}
@@ -0,0 +1,378 @@
+package csvparser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/amount"
+)
+
+const (
+	colTimestamp   = "timestamp"
+	colName        = "name"
+	colType        = "type"
+	colAmount      = "amount"
+	colStatus      = "status"
+	colDescription = "description"
+	colCurrency    = "currency"
+	colDebit       = "debit"
+	colCredit      = "credit"
+	colReference   = "reference"
+)
+
+// defaultColumns is the fixed order used when a file has no header row.
+var defaultColumns = []string{colTimestamp, colName, colType, colAmount, colStatus, colDescription}
+
+// A headerless file may carry the currency as an optional seventh column.
+var defaultColumnsWithCurrency = append(append([]string{}, defaultColumns...), colCurrency)
+
+var requiredColumns = []string{colTimestamp, colName, colStatus}
+
+// amountStyle is how a file tells credits from debits.
+type amountStyle int
+
+const (
+	// amountTyped is an unsigned amount next to a CREDIT/DEBIT type column.
+	amountTyped amountStyle = iota
+	// amountSigned is a single amount column where negative means debit.
+	amountSigned
+	// amountSplit has separate debit and credit amount columns, one of
+	// which is filled per row.
+	amountSplit
+	// amountMarked is one column holding the amount and a direction marker
+	// such as "1.500.000,00 DB".
+	amountMarked
+)
+
+// columnAliases lists the header names accepted for each known column,
+// compared after normalizeHeader.
+var columnAliases = map[string][]string{
+	colTimestamp:   {"timestamp", "date", "time", "datetime", "transaction date", "transaction time", "posting date", "booking date"},
+	colName:        {"name", "payee", "payer", "counterparty", "merchant", "beneficiary"},
+	colType:        {"type", "transaction type", "direction", "dr/cr", "debit/credit"},
+	colAmount:      {"amount", "value", "transaction amount"},
+	colStatus:      {"status", "state", "transaction status"},
+	colDescription: {"description", "desc", "memo", "details", "narrative", "remarks", "note"},
+	colCurrency:    {"currency", "ccy", "currency code", "cur"},
+	colDebit:       {"debit", "debit amount", "withdrawal", "withdrawals", "money out", "paid out"},
+	colCredit:      {"credit", "credit amount", "deposit", "deposits", "money in", "paid in"},
+	colReference:   {"reference", "ref", "bank reference", "reference number", "transaction reference", "no referensi", "referensi"},
+}
+
+var aliasIndex = func() map[string]string {
+	index := make(map[string]string)
+	for column, aliases := range columnAliases {
+		for _, alias := range aliases {
+			index[alias] = column
+		}
+	}
+	return index
+}()
+
+type extraColumn struct {
+	index int
+	key   string
+}
+
+// layout maps the known columns of a file to their position in a record.
+// Columns that are not recognised are kept as extras and end up in the
+// transaction metadata.
+type layout struct {
+	index   map[string]int
+	extras  []extraColumn
+	width   int
+	amounts amountStyle
+
+	values   map[string]map[string]string
+	defaults map[string]string
+	decimal  rune
+}
+
+func newLayout(width int, opts Options) *layout {
+	l := &layout{
+		index:    make(map[string]int),
+		width:    width,
+		values:   make(map[string]map[string]string, len(opts.Values)),
+		defaults: opts.Defaults,
+		decimal:  opts.DecimalSeparator,
+	}
+	for column, translations := range opts.Values {
+		upper := make(map[string]string, len(translations))
+		for from, to := range translations {
+			upper[strings.ToUpper(strings.TrimSpace(from))] = to
+		}
+		l.values[column] = upper
+	}
+	return l
+}
+
+func defaultLayout(width int, opts Options) *layout {
+	columns := defaultColumns
+	if width == len(defaultColumnsWithCurrency) {
+		columns = defaultColumnsWithCurrency
+	}
+	l := newLayout(len(columns), opts)
+	for i, column := range columns {
+		l.index[column] = i
+	}
+	return l
+}
+
+func normalizeHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(h))
+	h = strings.NewReplacer("_", " ", "-", " ", ".", " ").Replace(h)
+	return strings.Join(strings.Fields(h), " ")
+}
+
+// headerLayout builds a layout from a header row. It reports false when the
+// record does not look like a header, i.e. fewer than two of its cells are
+// known column names.
+func headerLayout(record []string, opts Options) (*layout, bool, error) {
+	l := newLayout(len(record), opts)
+	known := 0
+	for i, cell := range record {
+		name := normalizeHeader(cell)
+		column, ok := aliasIndex[name]
+		if ok {
+			known++
+			if _, taken := l.index[column]; !taken {
+				l.index[column] = i
+				continue
+			}
+		}
+		if name == "" {
+			continue
+		}
+		l.extras = append(l.extras, extraColumn{index: i, key: strings.ReplaceAll(name, " ", "_")})
+	}
+
+	if known < 2 {
+		return nil, false, nil
+	}
+	return l, true, l.resolve()
+}
+
+// profileLayout builds a layout from the exact header names in
+// opts.Columns. It reports false when the record lacks any of them, so
+// preamble rows can be skipped.
+func profileLayout(record []string, opts Options) (*layout, bool, error) {
+	cells := make(map[string]int, len(record))
+	for i, cell := range record {
+		name := normalizeHeader(cell)
+		if _, seen := cells[name]; !seen && name != "" {
+			cells[name] = i
+		}
+	}
+
+	l := newLayout(len(record), opts)
+	mapped := make(map[int]bool, len(opts.Columns))
+	for column, header := range opts.Columns {
+		i, ok := cells[normalizeHeader(header)]
+		if !ok {
+			return nil, false, nil
+		}
+		l.index[column] = i
+		mapped[i] = true
+	}
+	for i, cell := range record {
+		name := normalizeHeader(cell)
+		if name != "" && !mapped[i] {
+			l.extras = append(l.extras, extraColumn{index: i, key: strings.ReplaceAll(name, " ", "_")})
+		}
+	}
+	return l, true, l.resolve()
+}
+
+// ValidateColumns checks the column names used as keys of Options.Columns.
+func ValidateColumns(columns map[string]string) error {
+	for column := range columns {
+		if _, ok := columnAliases[column]; !ok {
+			return fmt.Errorf("unknown column %q", column)
+		}
+	}
+	return nil
+}
+
+// resolve checks that the required columns are present, or have a default,
+// and picks how credits are told from debits.
+func (l *layout) resolve() error {
+	for _, column := range requiredColumns {
+		if _, ok := l.index[column]; !ok && l.defaults[column] == "" {
+			return fmt.Errorf("invalid header: missing required column %q", column)
+		}
+	}
+
+	// Without a type column the direction comes from the sign of the
+	// amount or from which of the debit/credit columns is filled.
+	typeIndex, hasType := l.index[colType]
+	amountIndex, hasAmount := l.index[colAmount]
+	_, hasDebit := l.index[colDebit]
+	_, hasCredit := l.index[colCredit]
+	switch {
+	case hasType && hasAmount && typeIndex == amountIndex:
+		l.amounts = amountMarked
+	case hasType && hasAmount:
+		l.amounts = amountTyped
+	case hasAmount:
+		l.amounts = amountSigned
+	case hasDebit && hasCredit:
+		l.amounts = amountSplit
+	default:
+		return fmt.Errorf("invalid header: missing required column %q, or %q and %q", colAmount, colDebit, colCredit)
+	}
+	return nil
+}
+
+// raw returns the cell of a column, or its default when the column is
+// missing or empty.
+func (l *layout) raw(record []string, column string) string {
+	i, ok := l.index[column]
+	if !ok || i >= len(record) {
+		return l.defaults[column]
+	}
+	v := strings.TrimSpace(record[i])
+	if v == "" {
+		return l.defaults[column]
+	}
+	return v
+}
+
+// value returns the cell of a column after the configured translations.
+func (l *layout) value(record []string, column string) string {
+	v := l.raw(record, column)
+	if translated, ok := l.values[column][strings.ToUpper(v)]; ok {
+		return translated
+	}
+	return v
+}
+
+// direction reads a type cell or marker such as "DB" or "CREDIT".
+func (l *layout) direction(v string) (domain.TransactionType, bool) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	if translated, ok := l.values[colType][v]; ok {
+		v = strings.ToUpper(translated)
+	}
+	switch t := domain.TransactionType(v); t {
+	case domain.TypeCredit, domain.TypeDebit:
+		return t, true
+	}
+	return "", false
+}
+
+func (l *layout) parseAmount(v string, exponent int) (int64, error) {
+	return amount.ParseLocale(v, exponent, l.decimal)
+}
+
+// amount reads the direction and the unsigned amount of a record according
+// to the amount style of the layout.
+func (l *layout) amount(record []string, exponent int, line int) (domain.TransactionType, int64, error) {
+	switch l.amounts {
+	case amountMarked:
+		return l.markedAmount(record, exponent, line)
+
+	case amountSigned:
+		minor, err := l.parseAmount(l.raw(record, colAmount), exponent)
+		if err != nil {
+			return "", 0, &domain.RowError{Line: line, Field: "amount", Reason: err.Error()}
+		}
+		if minor < 0 {
+			return domain.TypeDebit, -minor, nil
+		}
+		return domain.TypeCredit, minor, nil
+
+	case amountSplit:
+		rawDebit, rawCredit := l.raw(record, colDebit), l.raw(record, colCredit)
+		if isBlankAmount(rawDebit) && isBlankAmount(rawCredit) {
+			return "", 0, &domain.RowError{Line: line, Field: "amount", Reason: "neither debit nor credit is set"}
+		}
+		debit, err := l.parseOptionalAmount(rawDebit, exponent)
+		if err != nil {
+			return "", 0, &domain.RowError{Line: line, Field: "debit", Reason: err.Error()}
+		}
+		credit, err := l.parseOptionalAmount(rawCredit, exponent)
+		if err != nil {
+			return "", 0, &domain.RowError{Line: line, Field: "credit", Reason: err.Error()}
+		}
+		// Some banks write debits as negative numbers in the debit column.
+		if debit < 0 {
+			debit = -debit
+		}
+		switch {
+		case debit != 0 && credit != 0:
+			return "", 0, &domain.RowError{Line: line, Field: "amount", Reason: "both debit and credit are set"}
+		case debit != 0:
+			return domain.TypeDebit, debit, nil
+		case credit < 0:
+			return "", 0, &domain.RowError{Line: line, Field: "credit", Reason: "negative credit amount"}
+		default:
+			return domain.TypeCredit, credit, nil
+		}
+	}
+
+	rawType := l.raw(record, colType)
+	txType, ok := l.direction(rawType)
+	if !ok {
+		return "", 0, &domain.RowError{Line: line, Field: "type", Reason: fmt.Sprintf("unknown transaction type %q", rawType)}
+	}
+	minor, err := l.parseAmount(l.raw(record, colAmount), exponent)
+	if err != nil {
+		return "", 0, &domain.RowError{Line: line, Field: "amount", Reason: err.Error()}
+	}
+	return txType, minor, nil
+}
+
+// isBlankAmount reports whether a debit or credit cell is empty; some
+// exports put a dash in the unused column.
+func isBlankAmount(value string) bool {
+	return value == "" || value == "-"
+}
+
+func (l *layout) parseOptionalAmount(value string, exponent int) (int64, error) {
+	if isBlankAmount(value) {
+		return 0, nil
+	}
+	return l.parseAmount(value, exponent)
+}
+
+// markedAmount reads a cell such as "1.500.000,00 DB" or "CR 250.000",
+// where the marker may come before or after the amount.
+func (l *layout) markedAmount(record []string, exponent int, line int) (domain.TransactionType, int64, error) {
+	cell := l.raw(record, colAmount)
+	fields := strings.Fields(cell)
+	if len(fields) >= 2 {
+		if txType, ok := l.direction(fields[len(fields)-1]); ok {
+			return l.unsignedAmount(txType, strings.Join(fields[:len(fields)-1], ""), exponent, line)
+		}
+		if txType, ok := l.direction(fields[0]); ok {
+			return l.unsignedAmount(txType, strings.Join(fields[1:], ""), exponent, line)
+		}
+	}
+	return "", 0, &domain.RowError{Line: line, Field: "type", Reason: fmt.Sprintf("missing debit/credit marker in %q", cell)}
+}
+
+func (l *layout) unsignedAmount(txType domain.TransactionType, value string, exponent int, line int) (domain.TransactionType, int64, error) {
+	minor, err := l.parseAmount(value, exponent)
+	if err != nil {
+		return "", 0, &domain.RowError{Line: line, Field: "amount", Reason: err.Error()}
+	}
+	return txType, minor, nil
+}
+
+func (l *layout) metadata(record []string) map[string]string {
+	var metadata map[string]string
+	for _, extra := range l.extras {
+		if extra.index >= len(record) {
+			continue
+		}
+		v := strings.TrimSpace(record[extra.index])
+		if v == "" {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string, len(l.extras))
+		}
+		metadata[extra.key] = v
+	}
+	return metadata
+}
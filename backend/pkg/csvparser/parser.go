@@ -1,90 +1,224 @@
 package csvparser
 
 import (
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/timeparse"
 )
 
+type Options struct {
+	// Lenient keeps the valid rows and records every rejected line in the
+	// report instead of failing on the first bad row.
+	Lenient bool
+	// Currency is used for rows without a currency column. It defaults to
+	// domain.DefaultCurrency.
+	Currency string
+	// TimeLayouts restricts the accepted timestamp formats (Go layouts or
+	// timeparse.LayoutUnix/LayoutUnixMilli). When empty the format is
+	// detected per value.
+	TimeLayouts []string
+	// Location is the timezone for timestamps without an offset. It
+	// defaults to UTC.
+	Location *time.Location
+	// Delimiter separates the fields. It defaults to a comma; see
+	// DetectDelimiter.
+	Delimiter rune
+
+	// Columns maps columns (timestamp, name, type, amount, debit, credit,
+	// status, description, currency) to the exact header names of a bank
+	// export, replacing the alias matching. Rows before that header are
+	// skipped. Type and amount may name the same header when amounts carry
+	// a marker, as in "1.500.000,00 DB".
+	Columns map[string]string
+	// Values translates cell values per column, e.g. "DB" to "DEBIT" for
+	// type. Keys are compared case-insensitively.
+	Values map[string]map[string]string
+	// Defaults fills columns that are missing or empty, e.g. status.
+	Defaults map[string]string
+	// DecimalSeparator is '.' unless set to ',', which reads Indonesian
+	// amounts such as 1.234.567,89.
+	DecimalSeparator rune
+}
+
+// maxPreambleRows bounds how far the header named by Options.Columns is
+// searched for.
+const maxPreambleRows = 20
+
 func Parse(fileReader io.Reader) ([]domain.Transaction, error) {
+	transactions, _, err := ParseWithOptions(fileReader, Options{})
+	return transactions, err
+}
+
+func ParseWithOptions(fileReader io.Reader, opts Options) ([]domain.Transaction, *domain.ParseReport, error) {
+	transactions := make([]domain.Transaction, 0)
+	report, err := Stream(fileReader, opts, func(tx domain.Transaction, _ int) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return transactions, report, nil
+}
+
+// Stream parses the file row by row and passes every valid transaction to
+// emit, so the file is never held in memory as a whole. A *domain.RowError
+// returned by emit is handled like a parse error of that row, so lenient
+// mode rejects only that row; any other error from emit stops parsing.
+func Stream(fileReader io.Reader, opts Options, emit domain.EmitFunc) (*domain.ParseReport, error) {
 	reader := csv.NewReader(fileReader)
 	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	if len(opts.Columns) > 0 {
+		if err := ValidateColumns(opts.Columns); err != nil {
+			return nil, err
+		}
+		// Preamble rows have a different width than the table.
+		reader.FieldsPerRecord = -1
+	}
 
-	transactions := make([]domain.Transaction, 0)
+	report := &domain.ParseReport{Errors: make([]domain.RowError, 0)}
 	lineNumber := -1
 
+	defaultCurrency := strings.ToUpper(opts.Currency)
+	if defaultCurrency == "" {
+		defaultCurrency = domain.DefaultCurrency
+	}
+
+	var cols *layout
+
 	parseRecord := func(record []string, ln int) error {
-		if len(record) != 6 {
-			return fmt.Errorf("invalid format on line %d: expected 6 fields, got %d", ln, len(record))
+		if len(record) != cols.width {
+			return &domain.RowError{Line: ln, Field: "format", Reason: fmt.Sprintf("expected %d fields, got %d", cols.width, len(record))}
 		}
 
-		timestamp, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
+		timestamp, err := timeparse.Parse(cols.value(record, colTimestamp), opts.TimeLayouts, opts.Location)
 		if err != nil {
-			return fmt.Errorf("invalid timestamp on line %d", ln)
+			return &domain.RowError{Line: ln, Field: "timestamp", Reason: err.Error()}
 		}
 
-		name := strings.TrimSpace(record[1])
-		txType := domain.TransactionType(strings.ToUpper(strings.TrimSpace(record[2])))
-		if txType != domain.TypeCredit && txType != domain.TypeDebit {
-			return fmt.Errorf("invalid transaction type on line %d: %s", ln, record[2])
+		name := cols.value(record, colName)
+
+		currency := strings.ToUpper(cols.value(record, colCurrency))
+		if currency == "" {
+			currency = defaultCurrency
+		}
+		if !isCurrencyCode(currency) {
+			return &domain.RowError{Line: ln, Field: "currency", Reason: fmt.Sprintf("invalid currency code %q", currency)}
 		}
 
-		amount, err := strconv.ParseInt(strings.TrimSpace(record[3]), 10, 64)
+		txType, minor, err := cols.amount(record, domain.CurrencyExponent(currency), ln)
 		if err != nil {
-			return fmt.Errorf("invalid amount on line %d: %w", ln, err)
+			return err
 		}
 
-		status := domain.TransactionStatus(strings.ToUpper(strings.TrimSpace(record[4])))
+		rawStatus := cols.value(record, colStatus)
+		status := domain.TransactionStatus(strings.ToUpper(rawStatus))
 		if status != domain.StatusSuccess && status != domain.StatusFailed && status != domain.StatusPending {
-			return fmt.Errorf("invalid status on line %d: %s", ln, record[4])
+			return &domain.RowError{Line: ln, Field: "status", Reason: fmt.Sprintf("unknown status %q", rawStatus)}
 		}
 
-		description := strings.TrimSpace(record[5])
+		description := cols.value(record, colDescription)
 
-		transactions = append(transactions, domain.Transaction{
-			Timestamp:   time.Unix(timestamp, 0),
+		return emit(domain.Transaction{
+			Timestamp:   timestamp,
 			Name:        name,
 			Type:        txType,
-			Amount:      amount,
+			Amount:      domain.NewMoney(minor, currency),
 			Status:      status,
 			Description: description,
-		})
-		return nil
+			Reference:   cols.value(record, colReference),
+			Metadata:    cols.metadata(record),
+		}, ln)
 	}
 
-	firstRecord, err := reader.Read()
-	lineNumber++
-	if err == io.EOF {
-		return transactions, nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to read csv on line %d: %w", lineNumber, err)
+	// handleRecord counts the row and either aborts (strict) or records the
+	// rejection and carries on (lenient).
+	handleRecord := func(record []string, ln int) error {
+		report.TotalRows++
+		err := parseRecord(record, ln)
+		if err == nil {
+			report.AcceptedRows++
+			return nil
+		}
+
+		var rowErr *domain.RowError
+		if !opts.Lenient || !errors.As(err, &rowErr) {
+			return err
+		}
+		report.RejectedRows++
+		report.Errors = append(report.Errors, *rowErr)
+		return nil
 	}
 
-	isHeader := false
-	if len(firstRecord) == 6 {
-		expected := []string{"timestamp", "name", "type", "amount", "status", "description"}
-		match := true
-		for i, f := range firstRecord {
-			if strings.ToLower(strings.TrimSpace(f)) != expected[i] {
-				match = false
-				break
-			}
+	// readError decides whether a csv read error is fatal. In lenient mode
+	// malformed lines are reported and skipped; the record is still returned
+	// for field count mismatches so it goes through the regular row checks.
+	readError := func(record []string, err error, ln int) (bool, error) {
+		var parseErr *csv.ParseError
+		if !opts.Lenient || !errors.As(err, &parseErr) {
+			return false, fmt.Errorf("failed to read csv on line %d: %w", ln, err)
 		}
-		if match {
-			isHeader = true
+		if errors.Is(err, csv.ErrFieldCount) {
+			return true, handleRecord(record, ln)
 		}
+		report.TotalRows++
+		report.RejectedRows++
+		report.Errors = append(report.Errors, domain.RowError{Line: ln, Field: "format", Reason: parseErr.Err.Error()})
+		return false, nil
 	}
 
-	if !isHeader {
-		if err := parseRecord(firstRecord, lineNumber); err != nil {
+	if len(opts.Columns) > 0 {
+		header, err := findHeader(reader, opts, &lineNumber)
+		if err != nil {
 			return nil, err
 		}
+		cols = header
+	} else {
+		firstRecord, err := reader.Read()
+		lineNumber++
+		if err == io.EOF {
+			return report, nil
+		}
+		if err != nil {
+			handled, err := readError(firstRecord, err, lineNumber)
+			if err != nil {
+				return nil, err
+			}
+			if handled {
+				firstRecord = nil
+			}
+		}
+
+		isHeader := false
+		cols = defaultLayout(len(firstRecord), opts)
+		if firstRecord != nil {
+			header, ok, err := headerLayout(firstRecord, opts)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				cols = header
+				isHeader = true
+			}
+		}
+
+		if !isHeader && firstRecord != nil {
+			if err := handleRecord(firstRecord, lineNumber); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	for {
@@ -94,13 +228,89 @@ func Parse(fileReader io.Reader) ([]domain.Transaction, error) {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to read csv on line %d: %w", lineNumber, err)
+			if _, err := readError(record, err, lineNumber); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
-		if err := parseRecord(record, lineNumber); err != nil {
+		if err := handleRecord(record, lineNumber); err != nil {
 			return nil, err
 		}
 	}
 
-	return transactions, nil
+	return report, nil
+}
+
+// findHeader skips the preamble some bank exports put above the table
+// (account number, period, ...) and returns the layout of the header row.
+func findHeader(reader *csv.Reader, opts Options, lineNumber *int) (*layout, error) {
+	for *lineNumber < maxPreambleRows {
+		*lineNumber++
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv on line %d: %w", *lineNumber, err)
+		}
+		l, ok, err := profileLayout(record, opts)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid header: no row with the columns %s", strings.Join(headerNames(opts.Columns), ", "))
+}
+
+// MatchesHeader reports whether one of the first rows of sample holds all
+// the header names of columns, as used to recognise a bank export.
+func MatchesHeader(sample []byte, delimiter rune, columns map[string]string) bool {
+	reader := csv.NewReader(bytes.NewReader(sample))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	opts := Options{Columns: columns}
+	for i := 0; i < maxPreambleRows; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			continue
+		}
+		if _, ok, _ := profileLayout(record, opts); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func headerNames(columns map[string]string) []string {
+	names := make([]string, 0, len(columns))
+	seen := make(map[string]bool, len(columns))
+	for _, header := range columns {
+		if !seen[header] {
+			seen[header] = true
+			names = append(names, fmt.Sprintf("%q", header))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
 }
@@ -2,9 +2,13 @@
 package csvparser
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/txid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,10 +25,69 @@ func TestParse_Success(t *testing.T) {
 
 	assert.Equal(t, int64(1624507883), transactions[0].Timestamp.Unix())
 	assert.Equal(t, "JOHN DOE", transactions[0].Name)
-	assert.Equal(t, int64(250000), transactions[0].Amount)
+	assert.Equal(t, domain.NewMoney(25000000, "IDR"), transactions[0].Amount)
 	assert.Equal(t, "restaurant", transactions[0].Description)
 }
 
+func TestParse_DecimalAmountsAndCurrency(t *testing.T) {
+	csvData := `timestamp,name,type,amount,currency,status,description
+1624507883,JOHN DOE,DEBIT,1234.56,USD,SUCCESS,restaurant
+1624512883,COMPANY A,CREDIT,"1,234.5",,SUCCESS,salary
+1624512999,TOKYO SHOP,DEBIT,1500,jpy,SUCCESS,souvenir`
+	reader := strings.NewReader(csvData)
+
+	transactions, err := Parse(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(transactions))
+	assert.Equal(t, domain.Money{Minor: 123456, Currency: "USD", Exponent: 2}, transactions[0].Amount)
+	assert.Equal(t, domain.Money{Minor: 123450, Currency: "IDR", Exponent: 2}, transactions[1].Amount)
+	assert.Equal(t, domain.Money{Minor: 1500, Currency: "JPY", Exponent: 0}, transactions[2].Amount)
+}
+
+func TestParse_HeaderlessCurrencyColumn(t *testing.T) {
+	csvData := `1624507883, JOHN DOE, DEBIT, 12.50, SUCCESS, restaurant, USD`
+	reader := strings.NewReader(csvData)
+
+	transactions, err := Parse(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "12.50", transactions[0].Amount.String())
+	assert.Equal(t, "USD", transactions[0].Amount.Currency)
+}
+
+func TestParseWithOptions_TimestampsAndTimezone(t *testing.T) {
+	csvData := `date,name,type,amount,status
+24/06/2024 14:03,JOHN DOE,DEBIT,250000,SUCCESS
+2024-06-24T14:03:00+09:00,COMPANY A,CREDIT,12000000,SUCCESS
+1719212580000,SHOP,DEBIT,1000,SUCCESS`
+	jakarta, _ := time.LoadLocation("Asia/Jakarta")
+
+	transactions, _, err := ParseWithOptions(strings.NewReader(csvData), Options{Location: jakarta})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-06-24T14:03:00+07:00", transactions[0].Timestamp.Format(time.RFC3339))
+	assert.Equal(t, "2024-06-24T14:03:00+09:00", transactions[1].Timestamp.Format(time.RFC3339))
+	assert.Equal(t, "2024-06-24T14:03:00+07:00", transactions[2].Timestamp.Format(time.RFC3339))
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	assert.Equal(t, ',', DetectDelimiter([]byte("a,b,c\n1,2,3\n")))
+	assert.Equal(t, ';', DetectDelimiter([]byte("timestamp;name;amount\n1624507883;JOHN;1.234,56\n1624507884;JANE;7,5\n")))
+	assert.Equal(t, '\t', DetectDelimiter([]byte("a\tb\tc\n1\t2\t3")))
+	assert.Equal(t, '|', DetectDelimiter([]byte("a|b|\"x,y\"\n1|2|3\n")))
+	assert.Equal(t, ',', DetectDelimiter([]byte("single")))
+}
+
+func TestParseWithOptions_Delimiter(t *testing.T) {
+	csvData := "1624507883;JOHN DOE;DEBIT;250000;SUCCESS;restaurant"
+
+	transactions, _, err := ParseWithOptions(strings.NewReader(csvData), Options{Delimiter: ';'})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "JOHN DOE", transactions[0].Name)
+}
+
 func TestParse_Error_InvalidFormat(t *testing.T) {
 	csvData := `1624507883, JOHN DOE, DEBIT, 250000, SUCCESS, restaurant
 1624512883, COMPANY A, CREDIT, 12000000, SUCCESS`
@@ -45,7 +108,196 @@ func TestParse_Error_InvalidAmount(t *testing.T) {
 
 	assert.Error(t, err)
 	assert.Nil(t, transactions)
-	assert.Contains(t, err.Error(), "invalid amount on line 0: strconv.ParseInt: parsing \"NOT_A_NUMBER\": invalid syntax")
+	assert.Contains(t, err.Error(), "invalid amount on line 0: invalid amount \"NOT_A_NUMBER\"")
+}
+
+func TestParseWithOptions_LenientReport(t *testing.T) {
+	csvData := `timestamp,name,type,amount,status,description
+1624507883, JOHN DOE, DEBIT, 250000, SUCCESS, restaurant
+1624512883, COMPANY A, CREDIT, 12000000, SUCCESS
+NOT_A_TIME, SHOP, DEBIT, 1000, SUCCESS, groceries
+1624512999, SHOP, DEBIT, 1000, UNKNOWN, groceries
+1624513000, SHOP, DEBIT, 5000, FAILED, groceries`
+	reader := strings.NewReader(csvData)
+
+	transactions, report, err := ParseWithOptions(reader, Options{Lenient: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(transactions))
+	assert.Equal(t, 5, report.TotalRows)
+	assert.Equal(t, 2, report.AcceptedRows)
+	assert.Equal(t, 3, report.RejectedRows)
+
+	assert.Equal(t, 2, report.Errors[0].Line)
+	assert.Equal(t, "format", report.Errors[0].Field)
+	assert.Equal(t, "expected 6 fields, got 5", report.Errors[0].Reason)
+	assert.Equal(t, "timestamp", report.Errors[1].Field)
+	assert.Equal(t, 4, report.Errors[2].Line)
+	assert.Equal(t, "status", report.Errors[2].Field)
+}
+
+func TestParseWithOptions_StrictStopsAtFirstError(t *testing.T) {
+	csvData := `1624507883, JOHN DOE, DEBIT, 250000, SUCCESS, restaurant
+1624507884, JOHN DOE, DEBIT, 250000, UNKNOWN, restaurant`
+	reader := strings.NewReader(csvData)
+
+	transactions, report, err := ParseWithOptions(reader, Options{})
+
+	assert.Error(t, err)
+	assert.Nil(t, transactions)
+	assert.Nil(t, report)
+	assert.Contains(t, err.Error(), "invalid status on line 1")
+}
+
+func TestParse_HeaderColumnMapping(t *testing.T) {
+	csvData := `Reference,Amount,Date,Branch,Transaction Type,Payee,Status,Memo
+REF-001,250000,1624507883,JAKARTA,DEBIT,JOHN DOE,SUCCESS,restaurant
+REF-002,12000000,1624512883,,CREDIT,COMPANY A,SUCCESS,salary`
+	reader := strings.NewReader(csvData)
+
+	transactions, err := Parse(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(transactions))
+
+	assert.Equal(t, int64(1624507883), transactions[0].Timestamp.Unix())
+	assert.Equal(t, "JOHN DOE", transactions[0].Name)
+	assert.Equal(t, domain.TypeDebit, transactions[0].Type)
+	assert.Equal(t, domain.NewMoney(25000000, "IDR"), transactions[0].Amount)
+	assert.Equal(t, "restaurant", transactions[0].Description)
+	assert.Equal(t, "REF-001", transactions[0].Reference)
+	assert.Equal(t, map[string]string{"branch": "JAKARTA"}, transactions[0].Metadata)
+
+	assert.Equal(t, "REF-002", transactions[1].Reference)
+	assert.Nil(t, transactions[1].Metadata)
+}
+
+func TestParse_ReferenceDrivesID(t *testing.T) {
+	csvData := `Date,Name,Type,Amount,Status,No. Referensi
+2024-06-01,COFFEE,DEBIT,25,SUCCESS,FT24153XYZ
+2024-06-01,COFFEE,DEBIT,25,SUCCESS,`
+	reader := strings.NewReader(csvData)
+
+	transactions, err := Parse(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(transactions))
+	assert.Equal(t, "FT24153XYZ", transactions[0].Reference)
+	assert.Nil(t, transactions[0].Metadata)
+
+	// The bank reference alone keys the row, whatever else it says.
+	renamed := transactions[0]
+	renamed.Name = "COFFEE SHOP"
+	assert.Equal(t, txid.Key(transactions[0]), txid.Key(renamed))
+	// Without one the content is the key.
+	assert.Equal(t, "", transactions[1].Reference)
+	assert.NotEqual(t, txid.Key(transactions[0]), txid.Key(transactions[1]))
+}
+
+func TestParse_HeaderWithoutDescription(t *testing.T) {
+	csvData := `STATUS,NAME,TYPE,AMOUNT,TIMESTAMP
+SUCCESS,JOHN DOE,DEBIT,250000,1624507883`
+	reader := strings.NewReader(csvData)
+
+	transactions, err := Parse(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(transactions))
+	assert.Equal(t, "", transactions[0].Description)
+	assert.Nil(t, transactions[0].Metadata)
+}
+
+func TestParse_Error_HeaderMissingColumn(t *testing.T) {
+	csvData := `date,name,type,status
+1624507883,JOHN DOE,DEBIT,SUCCESS`
+	reader := strings.NewReader(csvData)
+
+	transactions, err := Parse(reader)
+
+	assert.Error(t, err)
+	assert.Nil(t, transactions)
+	assert.Contains(t, err.Error(), `missing required column "amount", or "debit" and "credit"`)
+}
+
+func TestParse_SignedAmountWithoutType(t *testing.T) {
+	csvData := `date,name,amount,status
+1624507883,JOHN DOE,-250000,SUCCESS
+1624507884,COMPANY A,"1,000,000.50",SUCCESS`
+	reader := strings.NewReader(csvData)
+
+	transactions, err := Parse(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(transactions))
+	assert.Equal(t, domain.TypeDebit, transactions[0].Type)
+	assert.Equal(t, "250000.00", transactions[0].Amount.String())
+	assert.Equal(t, domain.TypeCredit, transactions[1].Type)
+	assert.Equal(t, "1000000.50", transactions[1].Amount.String())
+}
+
+func TestParse_SplitDebitCreditColumns(t *testing.T) {
+	csvData := `date,name,withdrawal,deposit,status
+1624507883,JOHN DOE,250000,,SUCCESS
+1624507884,COMPANY A,-,1000000,SUCCESS
+1624507885,JANE DOE,-5000,,SUCCESS`
+	reader := strings.NewReader(csvData)
+
+	transactions, err := Parse(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(transactions))
+	assert.Equal(t, domain.TypeDebit, transactions[0].Type)
+	assert.Equal(t, "250000.00", transactions[0].Amount.String())
+	assert.Equal(t, domain.TypeCredit, transactions[1].Type)
+	assert.Equal(t, "1000000.00", transactions[1].Amount.String())
+	assert.Equal(t, domain.TypeDebit, transactions[2].Type)
+	assert.Equal(t, "5000.00", transactions[2].Amount.String())
+}
+
+func TestParse_SplitColumnsReport(t *testing.T) {
+	csvData := `date,name,debit,credit,status
+1624507883,JOHN DOE,100,200,SUCCESS
+1624507884,COMPANY A,,,SUCCESS
+1624507885,JANE DOE,,300,SUCCESS`
+	reader := strings.NewReader(csvData)
+
+	transactions, report, err := ParseWithOptions(reader, Options{Lenient: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(transactions))
+	assert.Equal(t, []domain.RowError{
+		{Line: 1, Field: "amount", Reason: "both debit and credit are set"},
+		{Line: 2, Field: "amount", Reason: "neither debit nor credit is set"},
+	}, report.Errors)
+}
+
+func TestStream_EmitErrorStopsParsing(t *testing.T) {
+	stop := errors.New("stop")
+	emitted := 0
+
+	_, err := Stream(strings.NewReader(generateCSVData(10)), Options{Lenient: true}, func(tx domain.Transaction, line int) error {
+		emitted++
+		if emitted == 3 {
+			return stop
+		}
+		return nil
+	})
+
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 3, emitted)
+}
+
+func TestStream_EmitRowErrorRejectsRow(t *testing.T) {
+	report, err := Stream(strings.NewReader(generateCSVData(3)), Options{Lenient: true}, func(tx domain.Transaction, line int) error {
+		if line == 1 {
+			return &domain.RowError{Line: line, Field: "amount", Reason: "too large"}
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.AcceptedRows)
+	assert.Equal(t, []domain.RowError{{Line: 1, Field: "amount", Reason: "too large"}}, report.Errors)
 }
 
 func generateCSVData(rows int) string {
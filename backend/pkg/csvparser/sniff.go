@@ -0,0 +1,67 @@
+package csvparser
+
+import (
+	"bytes"
+	"strings"
+)
+
+var delimiterCandidates = []rune{',', ';', '\t', '|'}
+
+// DetectDelimiter picks the delimiter that splits the sample lines into the
+// same, largest number of fields. Separators inside quoted fields are
+// ignored. It falls back to a comma.
+func DetectDelimiter(sample []byte) rune {
+	lines := strings.Split(strings.ReplaceAll(string(sample), "\r\n", "\n"), "\n")
+	// The last line of a sample is usually cut off.
+	if len(lines) > 1 && !bytes.HasSuffix(sample, []byte("\n")) {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 10 {
+		lines = lines[:10]
+	}
+
+	best, bestScore := ',', 0
+	for _, candidate := range delimiterCandidates {
+		consistent := true
+		fields := -1
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			n := countOutsideQuotes(line, candidate)
+			if fields == -1 {
+				fields = n
+			} else if n != fields {
+				consistent = false
+			}
+		}
+		if fields <= 0 {
+			continue
+		}
+
+		// A delimiter that gives every line the same field count beats one
+		// that merely appears more often.
+		score := fields
+		if consistent {
+			score += 1000
+		}
+		if score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best
+}
+
+func countOutsideQuotes(line string, sep rune) int {
+	count := 0
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == sep && !quoted:
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,68 @@
+package csvparser
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+// StatementParser adapts the CSV parser to the parser registry. CSV has no
+// signature, so it is recognised by MIME type or extension only and is
+// usually also registered as the fallback.
+type StatementParser struct {
+	base Options
+}
+
+func New() *StatementParser {
+	return &StatementParser{}
+}
+
+// NewWithOptions returns a parser that applies the column mapping, value
+// translations, defaults and decimal separator of base to every file; the
+// other fields come from the upload.
+func NewWithOptions(base Options) *StatementParser {
+	return &StatementParser{base: base}
+}
+
+func (p *StatementParser) Format() domain.StatementFormat {
+	return domain.FormatCSV
+}
+
+func (p *StatementParser) Detect(src domain.SourceInfo) bool {
+	switch src.MIMEType {
+	case "text/csv", "application/csv", "text/tab-separated-values":
+		return true
+	}
+	switch src.Extension() {
+	case ".csv", ".tsv":
+		return true
+	}
+	return false
+}
+
+// sniffSize is how much of the file is inspected to pick the delimiter.
+const sniffSize = 4096
+
+func (p *StatementParser) Parse(r io.Reader, opts domain.ParseOptions, emit domain.EmitFunc) (*domain.ParseResult, error) {
+	delimiter := opts.Delimiter
+	if delimiter == 0 {
+		br := bufio.NewReaderSize(r, sniffSize)
+		head, _ := br.Peek(sniffSize)
+		delimiter = DetectDelimiter(head)
+		r = br
+	}
+
+	parseOpts := p.base
+	parseOpts.Lenient = opts.Lenient
+	parseOpts.Currency = opts.Currency
+	parseOpts.TimeLayouts = opts.TimeLayouts
+	parseOpts.Location = opts.Location
+	parseOpts.Delimiter = delimiter
+
+	report, err := Stream(r, parseOpts, emit)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ParseResult{Report: report, Delimiter: delimiter}, nil
+}
@@ -0,0 +1,152 @@
+package dedup
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+const (
+	RuleID        = "id"
+	RuleReference = "reference"
+	RuleFuzzy     = "fuzzy"
+)
+
+// DefaultWindow is how far apart the timestamps of a fuzzy match may be.
+// It covers exports that only carry the date next to ones with a time.
+const DefaultWindow = 24 * time.Hour
+
+// SettlementWindow is how long after a pending transaction its settled
+// counterpart may be booked.
+const SettlementWindow = 5 * 24 * time.Hour
+
+type amountKey struct {
+	txType   domain.TransactionType
+	currency string
+	minor    int64
+}
+
+// Index finds the stored transactions that uploaded ones duplicate. Every
+// stored transaction matches at most once, so two identical payments in
+// an upload only match two stored ones. A settled transaction is also
+// matched, once more, under the ID and timestamp of the row that settled
+// it.
+type Index struct {
+	window       time.Duration
+	transactions []domain.Transaction
+	of           []string
+	names        []string
+	used         []bool
+	byID         map[string]int
+	byReference  map[string][]int
+	byAmount     map[amountKey][]int
+}
+
+// NewIndex indexes existing, which should hold the transactions of one
+// account that are not flagged duplicates themselves.
+func NewIndex(existing []domain.Transaction, window time.Duration) *Index {
+	entries := make([]domain.Transaction, 0, len(existing))
+	of := make([]string, 0, len(existing))
+	for _, tx := range existing {
+		entries = append(entries, tx)
+		of = append(of, tx.ID)
+		for _, change := range tx.StatusHistory {
+			if change.TransactionID == "" || change.TransactionID == tx.ID {
+				continue
+			}
+			alias := tx
+			alias.ID = change.TransactionID
+			alias.Timestamp = change.Timestamp
+			entries = append(entries, alias)
+			of = append(of, tx.ID)
+		}
+	}
+
+	x := &Index{
+		window:       window,
+		transactions: entries,
+		of:           of,
+		names:        make([]string, len(entries)),
+		used:         make([]bool, len(entries)),
+		byID:         make(map[string]int, len(entries)),
+		byReference:  make(map[string][]int),
+		byAmount:     make(map[amountKey][]int, len(entries)),
+	}
+	for i, tx := range entries {
+		x.names[i] = normalizeName(tx.Name)
+		if tx.ID != "" {
+			x.byID[tx.ID] = i
+		}
+		if ref := strings.TrimSpace(tx.Reference); ref != "" {
+			x.byReference[ref] = append(x.byReference[ref], i)
+		}
+		key := keyOf(tx)
+		x.byAmount[key] = append(x.byAmount[key], i)
+	}
+	return x
+}
+
+// Match returns the stored transaction tx duplicates, trying its ID, its
+// reference and then a fuzzy match on type, amount, timestamp and name.
+func (x *Index) Match(tx domain.Transaction) *domain.DuplicateMatch {
+	if i, ok := x.byID[tx.ID]; ok && tx.ID != "" && !x.used[i] {
+		return x.take(i, RuleID)
+	}
+	if ref := strings.TrimSpace(tx.Reference); ref != "" {
+		for _, i := range x.byReference[ref] {
+			if !x.used[i] {
+				return x.take(i, RuleReference)
+			}
+		}
+	}
+
+	name := normalizeName(tx.Name)
+	best, bestGap := -1, time.Duration(0)
+	for _, i := range x.byAmount[keyOf(tx)] {
+		if x.used[i] || !similarNames(name, x.names[i]) {
+			continue
+		}
+		gap := tx.Timestamp.Sub(x.transactions[i].Timestamp).Abs()
+		if gap > x.window {
+			continue
+		}
+		if best < 0 || gap < bestGap {
+			best, bestGap = i, gap
+		}
+	}
+	if best >= 0 {
+		return x.take(best, RuleFuzzy)
+	}
+	return nil
+}
+
+func (x *Index) take(i int, rule string) *domain.DuplicateMatch {
+	x.used[i] = true
+	return &domain.DuplicateMatch{Of: x.of[i], Rule: rule}
+}
+
+func keyOf(tx domain.Transaction) amountKey {
+	return amountKey{txType: tx.Type, currency: tx.Amount.Currency, minor: tx.Amount.Minor}
+}
+
+// normalizeName lowercases a name and reduces it to its letters and
+// digits separated by single spaces.
+func normalizeName(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// similarNames accepts equal names and names where one contains the other,
+// as banks often shorten or prefix the counterparty.
+func similarNames(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if len(a) < 3 || len(b) < 3 {
+		return false
+	}
+	return strings.Contains(a, b) || strings.Contains(b, a)
+}
@@ -0,0 +1,74 @@
+// bank-statement-viewer/pkg/dedup/dedup_test.go
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+var day = time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+
+func tx(id, name string, at time.Time, minor int64) domain.Transaction {
+	return domain.Transaction{
+		ID:        id,
+		Timestamp: at,
+		Name:      name,
+		Type:      domain.TypeDebit,
+		Amount:    domain.NewMoney(minor, "IDR"),
+		Status:    domain.StatusSuccess,
+	}
+}
+
+func TestMatch_ID(t *testing.T) {
+	x := NewIndex([]domain.Transaction{tx("a", "COFFEE", day, 100)}, DefaultWindow)
+
+	assert.Equal(t, &domain.DuplicateMatch{Of: "a", Rule: RuleID}, x.Match(tx("a", "other", day.AddDate(0, 1, 0), 5)))
+	assert.Nil(t, x.Match(tx("a", "COFFEE", day, 100)), "a stored transaction matches only once")
+}
+
+func TestMatch_Reference(t *testing.T) {
+	stored := tx("a", "TRANSFER", day, 100)
+	stored.Reference = "FT123"
+	x := NewIndex([]domain.Transaction{stored}, DefaultWindow)
+
+	uploaded := tx("b", "TRANSFER IN", day.Add(48*time.Hour), 999)
+	uploaded.Reference = "FT123"
+	assert.Equal(t, &domain.DuplicateMatch{Of: "a", Rule: RuleReference}, x.Match(uploaded))
+}
+
+func TestMatch_Fuzzy(t *testing.T) {
+	x := NewIndex([]domain.Transaction{
+		tx("a", "Coffee Shop", day, 100),
+		tx("b", "Coffee Shop", day.Add(3*time.Hour), 100),
+		tx("c", "GROCERIES", day, 100),
+	}, DefaultWindow)
+
+	assert.Equal(t, "b", x.Match(tx("x", "COFFEE SHOP JAKARTA", day.Add(4*time.Hour), 100)).Of, "the closest timestamp wins")
+	assert.Equal(t, "a", x.Match(tx("y", "coffee-shop", day.Add(14*time.Hour), 100)).Of)
+	assert.Nil(t, x.Match(tx("z", "Coffee Shop", day, 100)), "both coffees are taken")
+
+	assert.Nil(t, x.Match(tx("z", "GROCERIES", day, 200)), "amounts differ")
+	assert.Nil(t, x.Match(tx("z", "GROCERIES", day.Add(25*time.Hour), 100)), "outside the window")
+	assert.Nil(t, x.Match(tx("z", "RESTAURANT", day, 100)), "names differ")
+
+	credit := tx("z", "GROCERIES", day, 100)
+	credit.Type = domain.TypeCredit
+	assert.Nil(t, x.Match(credit), "types differ")
+}
+
+func TestMatch_SettlingRow(t *testing.T) {
+	settledAt := day.Add(72 * time.Hour)
+	stored := tx("a", "HOTEL", day, 100)
+	stored.StatusHistory = []domain.StatusChange{
+		{Status: domain.StatusPending},
+		{Status: domain.StatusSuccess, TransactionID: "b", Timestamp: settledAt},
+	}
+	x := NewIndex([]domain.Transaction{stored}, DefaultWindow)
+
+	assert.Equal(t, &domain.DuplicateMatch{Of: "a", Rule: RuleID}, x.Match(tx("b", "HOTEL", settledAt, 100)))
+	assert.Equal(t, &domain.DuplicateMatch{Of: "a", Rule: RuleFuzzy}, x.Match(tx("c", "HOTEL", day.Add(time.Hour), 100)), "the pending row itself is still matched")
+	assert.Nil(t, x.Match(tx("d", "HOTEL", settledAt, 100)))
+}
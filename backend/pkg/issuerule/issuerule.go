@@ -0,0 +1,184 @@
+package issuerule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/amount"
+	"github.com/novanm/bank-viewer/backend/pkg/timeparse"
+)
+
+const (
+	ConditionStatus             = "status"
+	ConditionDebitAbove         = "debit_above"
+	ConditionWeekend            = "weekend"
+	ConditionMissingDescription = "missing_description"
+)
+
+// RuleConfig defines one issue rule. Only the parameters of the chosen
+// condition are read.
+type RuleConfig struct {
+	Code      string               `json:"code"`
+	Severity  domain.IssueSeverity `json:"severity"`
+	Condition string               `json:"condition"`
+
+	// Statuses lists the statuses that match the status condition.
+	Statuses []domain.TransactionStatus `json:"statuses,omitempty"`
+
+	// Max is the decimal limit of debit_above. With Currency set the rule
+	// only applies to that currency.
+	Max      string `json:"max,omitempty"`
+	Currency string `json:"currency,omitempty"`
+
+	// Timezone decides which day a weekend transaction falls on; it
+	// defaults to the timezone of the timestamp.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+type Config struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+// DefaultConfig is used when no rules file is configured: failed and
+// pending transactions are issues.
+func DefaultConfig() Config {
+	return Config{Rules: []RuleConfig{
+		{Code: "FAILED", Severity: domain.SeverityHigh, Condition: ConditionStatus, Statuses: []domain.TransactionStatus{domain.StatusFailed}},
+		{Code: "PENDING", Severity: domain.SeverityMedium, Condition: ConditionStatus, Statuses: []domain.TransactionStatus{domain.StatusPending}},
+	}}
+}
+
+type rule struct {
+	match domain.IssueMatch
+	check func(tx *domain.Transaction) bool
+}
+
+type Engine struct {
+	rules []rule
+}
+
+func New(cfg Config) (*Engine, error) {
+	e := &Engine{}
+	codes := make(map[string]bool)
+	for i, rc := range cfg.Rules {
+		r, err := buildRule(rc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid issue rule %d (%s): %w", i+1, rc.Code, err)
+		}
+		if codes[r.match.Code] {
+			return nil, fmt.Errorf("invalid issue rule %d: duplicate code %q", i+1, r.match.Code)
+		}
+		codes[r.match.Code] = true
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+// Default returns an engine for DefaultConfig.
+func Default() *Engine {
+	e, err := New(DefaultConfig())
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Load reads a JSON rules file.
+func Load(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issue rules: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse issue rules %s: %w", path, err)
+	}
+	return New(cfg)
+}
+
+// Match returns the rules tx matches, in the order they are configured.
+func (e *Engine) Match(tx domain.Transaction) []domain.IssueMatch {
+	var matches []domain.IssueMatch
+	for _, r := range e.rules {
+		if r.check(&tx) {
+			matches = append(matches, r.match)
+		}
+	}
+	return matches
+}
+
+func buildRule(rc RuleConfig) (rule, error) {
+	if strings.TrimSpace(rc.Code) == "" {
+		return rule{}, fmt.Errorf("code is required")
+	}
+	switch rc.Severity {
+	case domain.SeverityLow, domain.SeverityMedium, domain.SeverityHigh, domain.SeverityCritical:
+	default:
+		return rule{}, fmt.Errorf("unknown severity %q, expected low, medium, high or critical", rc.Severity)
+	}
+	r := rule{match: domain.IssueMatch{Code: rc.Code, Severity: rc.Severity}}
+
+	switch rc.Condition {
+	case ConditionStatus:
+		if len(rc.Statuses) == 0 {
+			return rule{}, fmt.Errorf("statuses is required")
+		}
+		statuses := make(map[domain.TransactionStatus]bool)
+		for _, s := range rc.Statuses {
+			s = domain.TransactionStatus(strings.ToUpper(string(s)))
+			switch s {
+			case domain.StatusSuccess, domain.StatusPending, domain.StatusFailed:
+			default:
+				return rule{}, fmt.Errorf("unknown status %q", s)
+			}
+			statuses[s] = true
+		}
+		r.check = func(tx *domain.Transaction) bool {
+			return statuses[tx.Status]
+		}
+	case ConditionDebitAbove:
+		check, err := debitAbove(rc)
+		if err != nil {
+			return rule{}, err
+		}
+		r.check = check
+	case ConditionWeekend:
+		var loc *time.Location
+		if rc.Timezone != "" {
+			l, err := timeparse.LoadLocation(rc.Timezone)
+			if err != nil {
+				return rule{}, err
+			}
+			loc = l
+		}
+		r.check = func(tx *domain.Transaction) bool {
+			t := tx.Timestamp
+			if loc != nil {
+				t = t.In(loc)
+			}
+			day := t.Weekday()
+			return day == time.Saturday || day == time.Sunday
+		}
+	case ConditionMissingDescription:
+		r.check = func(tx *domain.Transaction) bool {
+			return strings.TrimSpace(tx.Description) == ""
+		}
+	default:
+		return rule{}, fmt.Errorf("unknown condition %q", rc.Condition)
+	}
+	return r, nil
+}
+
+func debitAbove(rc RuleConfig) (func(*domain.Transaction) bool, error) {
+	limit, err := amount.ParseLimit(rc.Max, rc.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max: %w", err)
+	}
+	return func(tx *domain.Transaction) bool {
+		return tx.Type == domain.TypeDebit && limit.Exceeded(tx.Amount)
+	}, nil
+}
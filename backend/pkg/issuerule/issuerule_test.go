@@ -0,0 +1,79 @@
+// bank-statement-viewer/pkg/issuerule/issuerule_test.go
+package issuerule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// 2024-06-01 is a Saturday.
+var saturday = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestMatch(t *testing.T) {
+	e, err := New(Config{Rules: []RuleConfig{
+		{Code: "FAILED", Severity: domain.SeverityHigh, Condition: ConditionStatus, Statuses: []domain.TransactionStatus{"failed"}},
+		{Code: "LARGE_DEBIT", Severity: domain.SeverityCritical, Condition: ConditionDebitAbove, Max: "1000", Currency: "IDR"},
+		{Code: "WEEKEND", Severity: domain.SeverityLow, Condition: ConditionWeekend},
+		{Code: "NO_DESCRIPTION", Severity: domain.SeverityLow, Condition: ConditionMissingDescription},
+	}})
+	assert.NoError(t, err)
+
+	tx := domain.Transaction{Timestamp: saturday, Type: domain.TypeDebit, Amount: domain.NewMoney(100001, "IDR"), Status: domain.StatusFailed}
+	assert.Equal(t, []domain.IssueMatch{
+		{Code: "FAILED", Severity: domain.SeverityHigh},
+		{Code: "LARGE_DEBIT", Severity: domain.SeverityCritical},
+		{Code: "WEEKEND", Severity: domain.SeverityLow},
+		{Code: "NO_DESCRIPTION", Severity: domain.SeverityLow},
+	}, e.Match(tx))
+
+	tx = domain.Transaction{Timestamp: saturday.AddDate(0, 0, 2), Type: domain.TypeCredit, Amount: domain.NewMoney(100001, "IDR"), Status: domain.StatusSuccess, Description: "Invoice 12"}
+	assert.Empty(t, e.Match(tx))
+}
+
+func TestMatch_WeekendTimezone(t *testing.T) {
+	e, err := New(Config{Rules: []RuleConfig{{Code: "WEEKEND", Severity: domain.SeverityLow, Condition: ConditionWeekend, Timezone: "+07:00"}}})
+	assert.NoError(t, err)
+
+	// Friday 20:00 UTC is already Saturday in Jakarta.
+	friday := time.Date(2024, 5, 31, 20, 0, 0, 0, time.UTC)
+	assert.Len(t, e.Match(domain.Transaction{Timestamp: friday}), 1)
+	assert.Empty(t, e.Match(domain.Transaction{Timestamp: friday.Add(-6 * time.Hour)}))
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(Config{Rules: []RuleConfig{{Code: "X", Severity: "urgent", Condition: ConditionWeekend}}})
+	assert.EqualError(t, err, `invalid issue rule 1 (X): unknown severity "urgent", expected low, medium, high or critical`)
+
+	_, err = New(Config{Rules: []RuleConfig{{Code: "X", Severity: domain.SeverityLow, Condition: "holiday"}}})
+	assert.EqualError(t, err, `invalid issue rule 1 (X): unknown condition "holiday"`)
+
+	_, err = New(Config{Rules: []RuleConfig{{Severity: domain.SeverityLow, Condition: ConditionWeekend}}})
+	assert.Error(t, err)
+
+	_, err = New(Config{Rules: []RuleConfig{{Code: "X", Severity: domain.SeverityLow, Condition: ConditionStatus}}})
+	assert.Error(t, err)
+
+	_, err = New(Config{Rules: []RuleConfig{
+		{Code: "X", Severity: domain.SeverityLow, Condition: ConditionWeekend},
+		{Code: "X", Severity: domain.SeverityLow, Condition: ConditionMissingDescription},
+	}})
+	assert.EqualError(t, err, `invalid issue rule 2: duplicate code "X"`)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "issues.json")
+	err := os.WriteFile(path, []byte(`{"rules": [{"code": "NO_DESCRIPTION", "severity": "low", "condition": "missing_description"}]}`), 0o600)
+	assert.NoError(t, err)
+
+	e, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.IssueMatch{{Code: "NO_DESCRIPTION", Severity: domain.SeverityLow}}, e.Match(domain.Transaction{}))
+
+	_, err = Load(filepath.Join("..", "..", "config", "issues.json"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,378 @@
+package mt940parser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/amount"
+)
+
+type Options struct {
+	// Lenient keeps the valid :61: lines and reports the rejected ones
+	// instead of failing on the first bad entry.
+	Lenient bool
+	// Currency is used for entries seen before any :60a: balance. It
+	// defaults to domain.DefaultCurrency.
+	Currency string
+	// Location is the timezone of the statement dates, which carry no
+	// offset. It defaults to UTC.
+	Location *time.Location
+}
+
+// Sniff reports whether the beginning of a file looks like a SWIFT MT940
+// message: a transaction reference (:20:) followed by an account (:25:).
+func Sniff(head []byte) bool {
+	ref := bytes.Index(head, []byte(":20:"))
+	return ref >= 0 && bytes.Contains(head[ref:], []byte(":25:"))
+}
+
+type field struct {
+	tag   string
+	value string
+	line  int
+}
+
+func ParseWithOptions(fileReader io.Reader, opts Options) ([]domain.Transaction, []domain.Statement, *domain.ParseReport, error) {
+	transactions := make([]domain.Transaction, 0)
+	statements, report, err := Stream(fileReader, opts, func(tx domain.Transaction, _ int) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return transactions, statements, report, nil
+}
+
+// Stream reads one or more MT940 statements and passes each entry to emit
+// once its :86: information has been read. Every :61: statement line is a
+// booked entry, so all imported transactions are successful; the
+// :60a:/:62a: balances are kept as statement metadata.
+func Stream(fileReader io.Reader, opts Options, emit domain.EmitFunc) ([]domain.Statement, *domain.ParseReport, error) {
+	statements := make([]domain.Statement, 0)
+	report := &domain.ParseReport{Errors: make([]domain.RowError, 0)}
+
+	defaultCurrency := strings.ToUpper(opts.Currency)
+	if defaultCurrency == "" {
+		defaultCurrency = domain.DefaultCurrency
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var current *domain.Statement
+	var pending *domain.Transaction
+	pendingLine := 0
+	// hasInformation is set once the :86: field of the pending entry is read.
+	hasInformation := false
+
+	reject := func(err error) error {
+		var rowErr *domain.RowError
+		if !opts.Lenient || !errors.As(err, &rowErr) {
+			return err
+		}
+		report.RejectedRows++
+		report.Errors = append(report.Errors, *rowErr)
+		return nil
+	}
+
+	flushEntry := func() error {
+		if pending == nil {
+			return nil
+		}
+		tx := *pending
+		pending = nil
+		if tx.Name == "" {
+			tx.Name = tx.Reference
+		}
+		if err := emit(tx, pendingLine); err != nil {
+			return reject(err)
+		}
+		report.AcceptedRows++
+		current.Record(tx)
+		return nil
+	}
+
+	flushStatement := func() error {
+		if err := flushEntry(); err != nil {
+			return err
+		}
+		if current == nil {
+			return nil
+		}
+		current.CheckBalance()
+		statements = append(statements, *current)
+		current = nil
+		return nil
+	}
+
+	handle := func(f field) error {
+		if f.tag == "20" {
+			if err := flushStatement(); err != nil {
+				return err
+			}
+			current = &domain.Statement{ID: strings.TrimSpace(f.value)}
+			return nil
+		}
+		if current == nil {
+			return fmt.Errorf("invalid mt940 on line %d: field :%s: before :20:", f.line, f.tag)
+		}
+
+		switch f.tag {
+		case "25":
+			current.Account = strings.TrimSpace(f.value)
+		case "60F", "60M":
+			b, err := parseBalance(f.value, loc)
+			if err != nil {
+				return fmt.Errorf("invalid opening balance on line %d: %w", f.line, err)
+			}
+			if current.OpeningBalance == nil {
+				current.OpeningBalance = b
+				current.Currency = b.Amount.Currency
+			}
+		case "62F", "62M":
+			b, err := parseBalance(f.value, loc)
+			if err != nil {
+				return fmt.Errorf("invalid closing balance on line %d: %w", f.line, err)
+			}
+			current.ClosingBalance = b
+		case "61":
+			if err := flushEntry(); err != nil {
+				return err
+			}
+			report.TotalRows++
+			currency := current.Currency
+			if currency == "" {
+				currency = defaultCurrency
+			}
+			tx, err := parseStatementLine(f.value, f.line, currency, loc)
+			if err != nil {
+				return reject(err)
+			}
+			pending = &tx
+			pendingLine = f.line
+			hasInformation = false
+		case "86":
+			if pending != nil && !hasInformation {
+				applyInformation(pending, f.value)
+				hasInformation = true
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(fileReader)
+	var currentField *field
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		// SWIFT envelopes wrap the text block in {1:...}{2:...}{4: ... -}.
+		if i := strings.Index(text, "{4:"); i >= 0 {
+			text = text[i+3:]
+		}
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			continue
+		}
+		if trimmed == "-" || trimmed == "-}" || strings.HasPrefix(trimmed, "{") {
+			if currentField != nil {
+				if err := handle(*currentField); err != nil {
+					return nil, nil, err
+				}
+				currentField = nil
+			}
+			if err := flushStatement(); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		if tag, value, ok := splitTag(text); ok {
+			if currentField != nil {
+				if err := handle(*currentField); err != nil {
+					return nil, nil, err
+				}
+			}
+			currentField = &field{tag: tag, value: value, line: lineNumber}
+			continue
+		}
+
+		if currentField != nil {
+			currentField.value += "\n" + text
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read mt940: %w", err)
+	}
+	if currentField != nil {
+		if err := handle(*currentField); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := flushStatement(); err != nil {
+		return nil, nil, err
+	}
+
+	return statements, report, nil
+}
+
+var tagPattern = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):`)
+
+func splitTag(line string) (string, string, bool) {
+	m := tagPattern.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], line[len(m[0]):], true
+}
+
+// parseBalance reads a :60a:/:62a: value such as C240601IDR1000000,00.
+func parseBalance(value string, loc *time.Location) (*domain.StatementBalance, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 11 {
+		return nil, fmt.Errorf("value %q is too short", value)
+	}
+
+	mark := value[0]
+	date, err := time.ParseInLocation("060102", value[1:7], loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date in %q", value)
+	}
+	currency := strings.ToUpper(value[7:10])
+	v, err := parseAmount(value[10:], currency)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mark {
+	case 'C':
+	case 'D':
+		v = v.Neg()
+	default:
+		return nil, fmt.Errorf("invalid debit/credit mark %q", string(mark))
+	}
+
+	return &domain.StatementBalance{Amount: v, Date: date}, nil
+}
+
+// statementLinePattern matches the :61: subfields: value date, optional
+// entry date, debit/credit mark (with reversals), optional funds code,
+// amount, transaction type, customer reference and optional bank reference.
+var statementLinePattern = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?([0-9]+,[0-9]*)([NFS][A-Z0-9]{3})([^/\n]*)(?://([^\n]*))?(?:\n(.*))?`)
+
+func parseStatementLine(value string, line int, currency string, loc *time.Location) (domain.Transaction, error) {
+	m := statementLinePattern.FindStringSubmatch(value)
+	if m == nil {
+		return domain.Transaction{}, &domain.RowError{Line: line, Field: "61", Reason: fmt.Sprintf("unrecognised statement line %q", firstLine(value))}
+	}
+
+	timestamp, err := time.ParseInLocation("060102", m[1], loc)
+	if err != nil {
+		return domain.Transaction{}, &domain.RowError{Line: line, Field: "value date", Reason: err.Error()}
+	}
+
+	// A reversal of a credit (RC) takes money out of the account and a
+	// reversal of a debit (RD) puts it back.
+	var txType domain.TransactionType
+	switch m[3] {
+	case "C", "RD":
+		txType = domain.TypeCredit
+	default:
+		txType = domain.TypeDebit
+	}
+
+	v, err := parseAmount(m[5], currency)
+	if err != nil {
+		return domain.Transaction{}, &domain.RowError{Line: line, Field: "amount", Reason: err.Error()}
+	}
+
+	reference := strings.TrimSpace(m[7])
+	if reference == "NONREF" {
+		reference = ""
+	}
+	bankReference := strings.TrimSpace(m[8])
+
+	metadata := map[string]string{"transaction_type": m[6]}
+	if bankReference != "" {
+		metadata["bank_reference"] = bankReference
+	}
+	if supplementary := strings.TrimSpace(m[9]); supplementary != "" {
+		metadata["supplementary"] = supplementary
+	}
+	if m[3] == "RC" || m[3] == "RD" {
+		metadata["reversal"] = "true"
+	}
+
+	return domain.Transaction{
+		Timestamp: timestamp,
+		Type:      txType,
+		Amount:    v,
+		Status:    domain.StatusSuccess,
+		Reference: firstNonEmpty(bankReference, reference),
+		Metadata:  metadata,
+	}, nil
+}
+
+// applyInformation fills name and description from the :86: field. The
+// structured German layout (?20..?29 purpose, ?32/?33 counterparty) is used
+// when present; otherwise the first line is taken as the name.
+func applyInformation(tx *domain.Transaction, info string) {
+	if strings.Contains(info, "?") {
+		var purpose, name []string
+		for _, part := range strings.Split(strings.ReplaceAll(info, "\n", ""), "?")[1:] {
+			if len(part) < 2 {
+				continue
+			}
+			code, text := part[:2], strings.TrimSpace(part[2:])
+			switch {
+			case code >= "20" && code <= "29", code >= "60" && code <= "63":
+				purpose = append(purpose, text)
+			case code == "32" || code == "33":
+				name = append(name, text)
+			}
+		}
+		if len(purpose) > 0 || len(name) > 0 {
+			tx.Name = strings.Join(name, "")
+			tx.Description = strings.Join(purpose, " ")
+			return
+		}
+	}
+
+	lines := strings.Split(info, "\n")
+	tx.Name = strings.TrimSpace(lines[0])
+	tx.Description = strings.TrimSpace(strings.Join(lines, " "))
+}
+
+// parseAmount reads an MT940 amount, which uses a comma as the decimal
+// separator and has no thousands separators.
+func parseAmount(value, currency string) (domain.Money, error) {
+	minor, err := amount.Parse(strings.Replace(strings.TrimSpace(value), ",", ".", 1), domain.CurrencyExponent(currency))
+	if err != nil {
+		return domain.Money{}, err
+	}
+	return domain.NewMoney(minor, currency), nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
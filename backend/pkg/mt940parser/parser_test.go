@@ -0,0 +1,81 @@
+// bank-statement-viewer/pkg/mt940parser/parser_test.go
+package mt940parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+const statement = `:20:STMT240630
+:25:ID12BANK/0001
+:28C:00001/001
+:60F:C240601IDR1000000,00
+:61:2406100610C1000000,00NTRFPAYROLL//BANK-REF-1
+:86:COMPANY A
+salary June
+:61:2406120612D250000,NMSCNONREF
+:86:?20dinner?21team?32RESTAURANT
+:62F:C240630IDR1750000,00
+-`
+
+func TestParseWithOptions_Success(t *testing.T) {
+	transactions, statements, report, err := ParseWithOptions(strings.NewReader(statement), Options{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(transactions))
+	assert.Equal(t, 2, report.AcceptedRows)
+
+	assert.Equal(t, domain.TypeCredit, transactions[0].Type)
+	assert.Equal(t, "1000000.00", transactions[0].Amount.String())
+	assert.Equal(t, domain.StatusSuccess, transactions[0].Status)
+	assert.Equal(t, "BANK-REF-1", transactions[0].Reference)
+	assert.Equal(t, "COMPANY A", transactions[0].Name)
+	assert.Equal(t, "COMPANY A salary June", transactions[0].Description)
+	assert.Equal(t, "2024-06-10", transactions[0].Timestamp.Format("2006-01-02"))
+
+	assert.Equal(t, domain.TypeDebit, transactions[1].Type)
+	assert.Equal(t, "RESTAURANT", transactions[1].Name)
+	assert.Equal(t, "dinner team", transactions[1].Description)
+	assert.Equal(t, "", transactions[1].Reference)
+
+	assert.Equal(t, 1, len(statements))
+	stmt := statements[0]
+	assert.Equal(t, "STMT240630", stmt.ID)
+	assert.Equal(t, "ID12BANK/0001", stmt.Account)
+	assert.Equal(t, "IDR", stmt.Currency)
+	assert.Equal(t, "1000000.00", stmt.OpeningBalance.Amount.String())
+	assert.Equal(t, "1750000.00", stmt.ClosingBalance.Amount.String())
+	assert.True(t, *stmt.BalanceMatches)
+}
+
+func TestParseWithOptions_LenientReport(t *testing.T) {
+	data := strings.Replace(statement, ":61:2406120612D250000,NMSCNONREF", ":61:garbage", 1)
+
+	_, _, _, err := ParseWithOptions(strings.NewReader(data), Options{})
+	assert.Error(t, err)
+
+	transactions, statements, report, err := ParseWithOptions(strings.NewReader(data), Options{Lenient: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(transactions))
+	assert.Equal(t, 1, report.RejectedRows)
+	assert.Equal(t, 8, report.Errors[0].Line)
+	assert.False(t, *statements[0].BalanceMatches)
+}
+
+func TestParseWithOptions_SwiftEnvelope(t *testing.T) {
+	data := "{1:F01BANKIDJAAXXX0000000000}{2:O9401200240630BANKIDJAAXXX00000000002406301200N}{4:\n" + statement + "}"
+
+	transactions, statements, _, err := ParseWithOptions(strings.NewReader(data), Options{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(transactions))
+	assert.Equal(t, 1, len(statements))
+}
+
+func TestSniff(t *testing.T) {
+	assert.True(t, Sniff([]byte(statement)))
+	assert.False(t, Sniff([]byte("timestamp,name,type")))
+}
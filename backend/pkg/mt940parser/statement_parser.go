@@ -0,0 +1,38 @@
+package mt940parser
+
+import (
+	"io"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+// StatementParser adapts the MT940 parser to the parser registry.
+type StatementParser struct{}
+
+func New() *StatementParser {
+	return &StatementParser{}
+}
+
+func (p *StatementParser) Format() domain.StatementFormat {
+	return domain.FormatMT940
+}
+
+func (p *StatementParser) Detect(src domain.SourceInfo) bool {
+	switch src.Extension() {
+	case ".sta", ".mt940":
+		return true
+	}
+	return Sniff(src.Head)
+}
+
+func (p *StatementParser) Parse(r io.Reader, opts domain.ParseOptions, emit domain.EmitFunc) (*domain.ParseResult, error) {
+	statements, report, err := Stream(r, Options{
+		Lenient:  opts.Lenient,
+		Currency: opts.Currency,
+		Location: opts.Location,
+	}, emit)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ParseResult{Statements: statements, Report: report}, nil
+}
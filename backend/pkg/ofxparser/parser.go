@@ -0,0 +1,260 @@
+package ofxparser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/amount"
+)
+
+type Options struct {
+	// Lenient keeps the valid entries and reports the rejected ones instead
+	// of failing on the first bad STMTTRN.
+	Lenient bool
+	// Currency is used when the statement has no CURDEF. It defaults to
+	// domain.DefaultCurrency.
+	Currency string
+}
+
+// Sniff reports whether the beginning of a file looks like an OFX document,
+// either the 1.x SGML flavour (OFXHEADER:100) or 2.x XML (<?OFX ...?>).
+func Sniff(head []byte) bool {
+	upper := bytes.ToUpper(head)
+	return bytes.Contains(upper, []byte("OFXHEADER")) || bytes.Contains(upper, []byte("<OFX>"))
+}
+
+func Parse(fileReader io.Reader) ([]domain.Transaction, error) {
+	transactions, _, err := ParseWithOptions(fileReader, Options{})
+	return transactions, err
+}
+
+func ParseWithOptions(fileReader io.Reader, opts Options) ([]domain.Transaction, *domain.ParseReport, error) {
+	transactions := make([]domain.Transaction, 0)
+	report, err := Stream(fileReader, opts, func(tx domain.Transaction, _ int) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return transactions, report, nil
+}
+
+// Stream reads the STMTTRN entries of an OFX 1.x (SGML) or 2.x (XML)
+// statement and passes each valid one to emit. Both flavours are handled by
+// the same tokenizer: SGML leaf elements have no closing tag, so a leaf
+// value simply runs up to the next '<'.
+func Stream(fileReader io.Reader, opts Options, emit domain.EmitFunc) (*domain.ParseReport, error) {
+	report := &domain.ParseReport{Errors: make([]domain.RowError, 0)}
+
+	currency := strings.ToUpper(opts.Currency)
+	if currency == "" {
+		currency = domain.DefaultCurrency
+	}
+
+	var current map[string]string
+	currentLine := 0
+
+	finish := func() error {
+		report.TotalRows++
+		tx, err := toTransaction(current, currentLine, currency)
+		current = nil
+		if err == nil {
+			err = emit(tx, currentLine)
+		}
+		if err == nil {
+			report.AcceptedRows++
+			return nil
+		}
+
+		var rowErr *domain.RowError
+		if !opts.Lenient || !errors.As(err, &rowErr) {
+			return err
+		}
+		report.RejectedRows++
+		report.Errors = append(report.Errors, *rowErr)
+		return nil
+	}
+
+	reader := bufio.NewReader(fileReader)
+	line := 1
+	started := false
+	// valueTag is the element whose value is the text up to the next tag.
+	valueTag := ""
+	for {
+		text, err := reader.ReadString('<')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read ofx: %w", err)
+		}
+		atTag := err == nil
+		text = strings.TrimSuffix(text, "<")
+
+		if valueTag != "" {
+			value := strings.TrimSpace(unescape(text))
+			if valueTag == "CURDEF" && value != "" {
+				currency = strings.ToUpper(value)
+			}
+			if current != nil && value != "" {
+				if _, seen := current[valueTag]; !seen {
+					current[valueTag] = value
+				}
+			}
+			valueTag = ""
+		}
+		line += strings.Count(text, "\n")
+		if !atTag {
+			break
+		}
+
+		tagLine := line
+		raw, err := reader.ReadString('>')
+		if err == io.EOF {
+			return nil, fmt.Errorf("invalid ofx on line %d: unterminated tag", tagLine)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ofx: %w", err)
+		}
+		line += strings.Count(raw, "\n")
+		tag := strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(raw, ">")))
+
+		// Everything before the root element is header.
+		if !started {
+			started = tag == "OFX"
+			continue
+		}
+
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		if strings.HasPrefix(tag, "/") {
+			if tag == "/STMTTRN" && current != nil {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		if tag == "STMTTRN" {
+			if current != nil {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+			}
+			current = make(map[string]string)
+			currentLine = tagLine
+			continue
+		}
+
+		valueTag = tag
+	}
+
+	if !started {
+		return nil, errors.New("invalid ofx: missing <OFX> element")
+	}
+	if current != nil {
+		if err := finish(); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func toTransaction(fields map[string]string, line int, currency string) (domain.Transaction, error) {
+	timestamp, err := parseDate(fields["DTPOSTED"])
+	if err != nil {
+		return domain.Transaction{}, &domain.RowError{Line: line, Field: "DTPOSTED", Reason: err.Error()}
+	}
+
+	value, err := amount.Parse(fields["TRNAMT"], domain.CurrencyExponent(currency))
+	if err != nil {
+		return domain.Transaction{}, &domain.RowError{Line: line, Field: "TRNAMT", Reason: err.Error()}
+	}
+
+	txType := domain.TypeCredit
+	switch strings.ToUpper(fields["TRNTYPE"]) {
+	case "CREDIT":
+		txType = domain.TypeCredit
+	case "DEBIT":
+		txType = domain.TypeDebit
+	default:
+		if value < 0 {
+			txType = domain.TypeDebit
+		}
+	}
+
+	var metadata map[string]string
+	for _, key := range []string{"TRNTYPE", "CHECKNUM", "REFNUM", "SIC"} {
+		if v, ok := fields[key]; ok {
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			metadata[strings.ToLower(key)] = v
+		}
+	}
+
+	return domain.Transaction{
+		Timestamp:   timestamp,
+		Name:        fields["NAME"],
+		Type:        txType,
+		Amount:      domain.NewMoney(value, currency).Abs(),
+		Status:      domain.StatusSuccess,
+		Description: fields["MEMO"],
+		Reference:   fields["FITID"],
+		Metadata:    metadata,
+	}, nil
+}
+
+// parseDate understands the OFX datetime format
+// YYYYMMDD[HHMMSS[.XXX]][[+|-]offset[:TZNAME]]. Values without an offset
+// are in GMT, as the specification says.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("missing date")
+	}
+
+	value, zone, _ := strings.Cut(s, "[")
+	if dot := strings.IndexByte(value, '.'); dot >= 0 {
+		value = value[:dot]
+	}
+
+	layouts := map[int]string{8: "20060102", 12: "200601021504", 14: "20060102150405"}
+	layout, ok := layouts[len(value)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+
+	loc := time.UTC
+	if zone != "" {
+		offset, name, _ := strings.Cut(strings.TrimSuffix(zone, "]"), ":")
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone in date %q", s)
+		}
+		if name == "" {
+			name = offset
+		}
+		loc = time.FixedZone(name, int(hours*3600))
+	}
+
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+var entityReplacer = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ", "&amp;", "&")
+
+func unescape(s string) string {
+	return entityReplacer.Replace(s)
+}
@@ -0,0 +1,124 @@
+// bank-statement-viewer/pkg/ofxparser/parser_test.go
+package ofxparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>IDR
+<BANKTRANLIST>
+<DTSTART>20240601
+<DTEND>20240630
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240624120000.000[+7:WIB]
+<TRNAMT>-250000.00
+<FITID>TX-0001
+<NAME>JOHN DOE
+<MEMO>restaurant
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DIRECTDEP
+<DTPOSTED>20240625
+<TRNAMT>12000000
+<FITID>TX-0002
+<NAME>COMPANY A &amp; CO
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>`
+
+const xmlStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE"?>
+<OFX>
+  <BANKMSGSRSV1><STMTTRNRS><STMTRS>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>POS</TRNTYPE>
+        <DTPOSTED>20240624083000</DTPOSTED>
+        <TRNAMT>-50000</TRNAMT>
+        <FITID>XML-1</FITID>
+        <PAYEE><NAME>E-COMMERCE</NAME></PAYEE>
+        <MEMO>online order</MEMO>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+
+func TestParse_SGML(t *testing.T) {
+	transactions, err := Parse(strings.NewReader(sgmlStatement))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(transactions))
+
+	assert.Equal(t, domain.TypeDebit, transactions[0].Type)
+	assert.Equal(t, "250000.00", transactions[0].Amount.String())
+	assert.Equal(t, "TX-0001", transactions[0].Reference)
+	assert.Equal(t, "JOHN DOE", transactions[0].Name)
+	assert.Equal(t, "restaurant", transactions[0].Description)
+	assert.Equal(t, domain.StatusSuccess, transactions[0].Status)
+	assert.Equal(t, "2024-06-24T12:00:00+07:00", transactions[0].Timestamp.Format("2006-01-02T15:04:05-07:00"))
+
+	assert.Equal(t, domain.TypeCredit, transactions[1].Type)
+	assert.Equal(t, "COMPANY A & CO", transactions[1].Name)
+	assert.Equal(t, "directdep", strings.ToLower(transactions[1].Metadata["trntype"]))
+}
+
+func TestParse_XML(t *testing.T) {
+	transactions, err := Parse(strings.NewReader(xmlStatement))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(transactions))
+	assert.Equal(t, domain.TypeDebit, transactions[0].Type)
+	assert.Equal(t, "50000.00", transactions[0].Amount.String())
+	assert.Equal(t, "E-COMMERCE", transactions[0].Name)
+	assert.Equal(t, "XML-1", transactions[0].Reference)
+}
+
+func TestParse_CurrencyFromCURDEF(t *testing.T) {
+	data := strings.Replace(strings.Replace(sgmlStatement, "<CURDEF>IDR", "<CURDEF>USD", 1), "<TRNAMT>-250000.00", "<TRNAMT>-12.34", 1)
+
+	transactions, err := Parse(strings.NewReader(data))
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Money{Minor: 1234, Currency: "USD", Exponent: 2}, transactions[0].Amount)
+}
+
+func TestParseWithOptions_LenientReport(t *testing.T) {
+	data := strings.Replace(sgmlStatement, "<TRNAMT>12000000", "<TRNAMT>abc", 1)
+
+	transactions, report, err := ParseWithOptions(strings.NewReader(data), Options{Lenient: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(transactions))
+	assert.Equal(t, 1, report.RejectedRows)
+	assert.Equal(t, "TRNAMT", report.Errors[0].Field)
+	assert.Equal(t, 21, report.Errors[0].Line)
+}
+
+func TestParse_Error_MissingOFX(t *testing.T) {
+	transactions, err := Parse(strings.NewReader("timestamp,name"))
+
+	assert.Error(t, err)
+	assert.Nil(t, transactions)
+}
+
+func TestSniff(t *testing.T) {
+	assert.True(t, Sniff([]byte(sgmlStatement)))
+	assert.True(t, Sniff([]byte(xmlStatement)))
+	assert.False(t, Sniff([]byte("1624507883, JOHN DOE, DEBIT, 250000, SUCCESS, restaurant")))
+}
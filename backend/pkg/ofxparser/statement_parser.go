@@ -0,0 +1,38 @@
+package ofxparser
+
+import (
+	"io"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+// StatementParser adapts the OFX parser to the parser registry.
+type StatementParser struct{}
+
+func New() *StatementParser {
+	return &StatementParser{}
+}
+
+func (p *StatementParser) Format() domain.StatementFormat {
+	return domain.FormatOFX
+}
+
+func (p *StatementParser) Detect(src domain.SourceInfo) bool {
+	switch src.MIMEType {
+	case "application/x-ofx", "application/ofx", "application/vnd.intu.qfx":
+		return true
+	}
+	switch src.Extension() {
+	case ".ofx", ".qfx":
+		return true
+	}
+	return Sniff(src.Head)
+}
+
+func (p *StatementParser) Parse(r io.Reader, opts domain.ParseOptions, emit domain.EmitFunc) (*domain.ParseResult, error) {
+	report, err := Stream(r, Options{Lenient: opts.Lenient, Currency: opts.Currency}, emit)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ParseResult{Report: report}, nil
+}
@@ -0,0 +1,159 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/csvparser"
+)
+
+// Profile describes the CSV export of one bank: which header holds which
+// column, how its values translate and how its numbers are written. Every
+// profile is a statement parser of its own, recognised by its header row.
+type Profile struct {
+	// Name is also the format a client passes as ?profile= or ?format=.
+	Name string `json:"name"`
+	Bank string `json:"bank"`
+	// Delimiter is the field separator; it is sniffed when empty.
+	Delimiter string `json:"delimiter,omitempty"`
+	// Columns maps columns (timestamp, name, type, amount, debit, credit,
+	// status, description, currency) to header names; see
+	// csvparser.Options.Columns.
+	Columns map[string]string `json:"columns"`
+	// Values translates cell values per column, e.g. {"type": {"DB": "DEBIT"}}.
+	Values map[string]map[string]string `json:"values,omitempty"`
+	// Defaults fills columns the export does not have, e.g. status.
+	Defaults map[string]string `json:"defaults,omitempty"`
+	// DecimalSeparator is "," for amounts written as 1.234.567,89.
+	DecimalSeparator string `json:"decimal_separator,omitempty"`
+	// Currency applies when neither the file nor the upload names one.
+	Currency    string   `json:"currency,omitempty"`
+	TimeLayouts []string `json:"time_layouts,omitempty"`
+}
+
+// LoadDir reads every *.json profile in dir, sorted by file name.
+func LoadDir(dir string) ([]*Profile, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	profiles := make([]*Profile, 0, len(paths))
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		p, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("profile %s: duplicate name %q", path, p.Name)
+		}
+		seen[p.Name] = true
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+func Load(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+	var p Profile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse profile %s: %w", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func (p *Profile) validate() error {
+	p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(p.Columns) == 0 {
+		return fmt.Errorf("columns are required")
+	}
+	if err := csvparser.ValidateColumns(p.Columns); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(p.Delimiter) > 1 {
+		return fmt.Errorf("delimiter %q must be a single character", p.Delimiter)
+	}
+	switch p.DecimalSeparator {
+	case "", ".", ",":
+	default:
+		return fmt.Errorf("decimal_separator must be \".\" or \",\"")
+	}
+	return nil
+}
+
+func (p *Profile) Format() domain.StatementFormat {
+	return domain.StatementFormat(p.Name)
+}
+
+func (p *Profile) Detect(src domain.SourceInfo) bool {
+	delimiter := p.delimiter()
+	if delimiter == 0 {
+		delimiter = csvparser.DetectDelimiter(src.Head)
+	}
+	return csvparser.MatchesHeader(src.Head, delimiter, p.Columns)
+}
+
+func (p *Profile) Parse(r io.Reader, opts domain.ParseOptions, emit domain.EmitFunc) (*domain.ParseResult, error) {
+	delimiter := opts.Delimiter
+	if delimiter == 0 {
+		delimiter = p.delimiter()
+	}
+	currency := opts.Currency
+	if currency == "" {
+		currency = p.Currency
+	}
+	layouts := opts.TimeLayouts
+	if len(layouts) == 0 {
+		layouts = p.TimeLayouts
+	}
+
+	parser := csvparser.NewWithOptions(csvparser.Options{
+		Columns:          p.Columns,
+		Values:           p.Values,
+		Defaults:         p.Defaults,
+		DecimalSeparator: p.decimalSeparator(),
+	})
+	return parser.Parse(r, domain.ParseOptions{
+		Lenient:     opts.Lenient,
+		Currency:    currency,
+		Location:    opts.Location,
+		TimeLayouts: layouts,
+		Delimiter:   delimiter,
+	}, emit)
+}
+
+func (p *Profile) delimiter() rune {
+	r, _ := utf8.DecodeRuneInString(p.Delimiter)
+	if r == utf8.RuneError {
+		return 0
+	}
+	return r
+}
+
+func (p *Profile) decimalSeparator() rune {
+	if p.DecimalSeparator == "," {
+		return ','
+	}
+	return '.'
+}
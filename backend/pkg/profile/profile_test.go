@@ -0,0 +1,126 @@
+// bank-statement-viewer/pkg/profile/profile_test.go
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadProfiles(t *testing.T) map[string]*Profile {
+	profiles, err := LoadDir(filepath.Join("..", "..", "config", "profiles"))
+	assert.NoError(t, err)
+
+	byName := make(map[string]*Profile, len(profiles))
+	for _, p := range profiles {
+		byName[p.Name] = p
+	}
+	return byName
+}
+
+func parse(t *testing.T, p *Profile, data string) ([]domain.Transaction, *domain.ParseResult) {
+	var transactions []domain.Transaction
+	result, err := p.Parse(strings.NewReader(data), domain.ParseOptions{}, func(tx domain.Transaction, _ int) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	assert.NoError(t, err)
+	return transactions, result
+}
+
+const bcaExport = `Informasi Rekening - Mutasi Rekening
+No. rekening : 1234567890
+Periode : 01/06/2024 - 30/06/2024
+Tanggal,Keterangan,Cabang,Mutasi,Saldo
+03/06/2024,TRSF E-BANKING CR COMPANY A,0000,"12.000.000,00 CR","13.000.000,00"
+05/06/2024,TARIKAN ATM,0998,"1.500.000,50 DB","11.499.999,50"`
+
+func TestLoadDir_ShippedProfiles(t *testing.T) {
+	profiles := loadProfiles(t)
+
+	for _, name := range []string{"bca", "mandiri", "bni", "bri"} {
+		assert.Contains(t, profiles, name)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	profiles := loadProfiles(t)
+
+	assert.True(t, profiles["bca"].Detect(domain.SourceInfo{Head: []byte(bcaExport)}))
+	assert.False(t, profiles["mandiri"].Detect(domain.SourceInfo{Head: []byte(bcaExport)}))
+	assert.False(t, profiles["bca"].Detect(domain.SourceInfo{Head: []byte("timestamp,name,type,amount,status\n")}))
+}
+
+func TestParse_BCAMarkedAmounts(t *testing.T) {
+	transactions, result := parse(t, loadProfiles(t)["bca"], bcaExport)
+
+	assert.Equal(t, 2, result.Report.AcceptedRows)
+	assert.Equal(t, domain.TypeCredit, transactions[0].Type)
+	assert.Equal(t, domain.NewMoney(1200000000, "IDR"), transactions[0].Amount)
+	assert.Equal(t, domain.StatusSuccess, transactions[0].Status)
+	assert.Equal(t, "TRSF E-BANKING CR COMPANY A", transactions[0].Name)
+	assert.Equal(t, "2024-06-03", transactions[0].Timestamp.Format("2006-01-02"))
+	assert.Equal(t, map[string]string{"cabang": "0000", "saldo": "13.000.000,00"}, transactions[0].Metadata)
+
+	assert.Equal(t, domain.TypeDebit, transactions[1].Type)
+	assert.Equal(t, "1500000.50", transactions[1].Amount.String())
+}
+
+func TestParse_MandiriSplitColumns(t *testing.T) {
+	data := `Tanggal Transaksi;Keterangan;Debit;Kredit;Saldo
+01/06/2024 08:15:00;BIAYA ADM;10.000,00;0,00;989.990,00
+02/06/2024 10:00:00;SETORAN TUNAI;0,00;250.000,00;1.239.990,00`
+
+	transactions, _ := parse(t, loadProfiles(t)["mandiri"], data)
+
+	assert.Equal(t, 2, len(transactions))
+	assert.Equal(t, domain.TypeDebit, transactions[0].Type)
+	assert.Equal(t, "10000.00", transactions[0].Amount.String())
+	assert.Equal(t, domain.TypeCredit, transactions[1].Type)
+	assert.Equal(t, "250000.00", transactions[1].Amount.String())
+}
+
+func TestParse_BNITranslatedType(t *testing.T) {
+	data := `Tanggal Transaksi,Uraian Transaksi,Tipe,Nominal,Saldo Akhir
+01/06/2024,TRANSFER KE JOHN DOE,D,"50.000,00","950.000,00"
+02/06/2024,GAJI,K,"5.000.000,00","5.950.000,00"`
+
+	transactions, _ := parse(t, loadProfiles(t)["bni"], data)
+
+	assert.Equal(t, domain.TypeDebit, transactions[0].Type)
+	assert.Equal(t, domain.TypeCredit, transactions[1].Type)
+	assert.Equal(t, "5000000.00", transactions[1].Amount.String())
+}
+
+func TestParse_UploadOverridesProfileCurrency(t *testing.T) {
+	data := `TGL_TRAN,DESK_TRAN,MUTASI_DEBET,MUTASI_KREDIT,SALDO_AKHIR_MUTASI
+2024-06-01 09:00:00,PEMBAYARAN,"1,250.00",0.00,"8,750.00"`
+
+	var transactions []domain.Transaction
+	_, err := loadProfiles(t)["bri"].Parse(strings.NewReader(data), domain.ParseOptions{Currency: "USD"}, func(tx domain.Transaction, _ int) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.NewMoney(125000, "USD"), transactions[0].Amount)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bank.json")
+
+	err := os.WriteFile(path, []byte(`{"name": "bank", "columns": {"saldo": "Saldo"}}`), 0o600)
+	assert.NoError(t, err)
+	_, err = Load(path)
+	assert.ErrorContains(t, err, `unknown column "saldo"`)
+
+	err = os.WriteFile(path, []byte(`{"name": "bank", "columns": {"amount": "Jumlah"}, "decimal_separator": "'"}`), 0o600)
+	assert.NoError(t, err)
+	_, err = Load(path)
+	assert.ErrorContains(t, err, "decimal_separator")
+}
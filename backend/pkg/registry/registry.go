@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+// Registry holds the statement parsers known to the service. Parsers are
+// asked in registration order, so formats recognised by their content
+// should be registered before catch-all ones such as CSV.
+type Registry struct {
+	mu       sync.RWMutex
+	parsers  []domain.StatementParser
+	fallback domain.StatementParser
+}
+
+func New() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(p domain.StatementParser) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.parsers = append(r.parsers, p)
+}
+
+// SetFallback chooses the parser used when no parser recognises a file.
+func (r *Registry) SetFallback(p domain.StatementParser) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fallback = p
+}
+
+func (r *Registry) Lookup(format domain.StatementFormat) (domain.StatementParser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.parsers {
+		if strings.EqualFold(string(p.Format()), string(format)) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported format %q, expected one of: %s", format, strings.Join(r.formats(), ", "))
+}
+
+func (r *Registry) Resolve(src domain.SourceInfo) (domain.StatementParser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.parsers {
+		if p.Detect(src) {
+			return p, nil
+		}
+	}
+	if r.fallback != nil {
+		return r.fallback, nil
+	}
+	return nil, fmt.Errorf("unrecognised statement format, expected one of: %s", strings.Join(r.formats(), ", "))
+}
+
+func (r *Registry) Formats() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.formats()
+}
+
+func (r *Registry) formats() []string {
+	formats := make([]string, 0, len(r.parsers))
+	for _, p := range r.parsers {
+		formats = append(formats, string(p.Format()))
+	}
+	return formats
+}
@@ -0,0 +1,60 @@
+// bank-statement-viewer/pkg/registry/registry_test.go
+package registry
+
+import (
+	"io"
+	"testing"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeParser struct {
+	format    domain.StatementFormat
+	extension string
+}
+
+func (f *fakeParser) Format() domain.StatementFormat { return f.format }
+
+func (f *fakeParser) Detect(src domain.SourceInfo) bool { return src.Extension() == f.extension }
+
+func (f *fakeParser) Parse(r io.Reader, opts domain.ParseOptions, emit domain.EmitFunc) (*domain.ParseResult, error) {
+	return &domain.ParseResult{}, nil
+}
+
+func TestResolve_FirstMatchWins(t *testing.T) {
+	r := New()
+	r.Register(&fakeParser{format: "a", extension: ".txt"})
+	r.Register(&fakeParser{format: "b", extension: ".txt"})
+
+	p, err := r.Resolve(domain.SourceInfo{Filename: "STATEMENT.TXT"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatementFormat("a"), p.Format())
+}
+
+func TestResolve_Fallback(t *testing.T) {
+	r := New()
+	r.Register(&fakeParser{format: "a", extension: ".a"})
+
+	_, err := r.Resolve(domain.SourceInfo{Filename: "statement.dat"})
+	assert.EqualError(t, err, "unrecognised statement format, expected one of: a")
+
+	fallback := &fakeParser{format: "csv"}
+	r.SetFallback(fallback)
+	p, err := r.Resolve(domain.SourceInfo{Filename: "statement.dat"})
+	assert.NoError(t, err)
+	assert.Equal(t, fallback, p)
+}
+
+func TestLookup(t *testing.T) {
+	r := New()
+	r.Register(&fakeParser{format: "mt940"})
+
+	p, err := r.Lookup("MT940")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatementFormat("mt940"), p.Format())
+
+	_, err = r.Lookup("qif")
+	assert.EqualError(t, err, `unsupported format "qif", expected one of: mt940`)
+}
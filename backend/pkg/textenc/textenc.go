@@ -0,0 +1,228 @@
+package textenc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type Encoding string
+
+const (
+	UTF8        Encoding = "utf-8"
+	UTF16LE     Encoding = "utf-16le"
+	UTF16BE     Encoding = "utf-16be"
+	Windows1252 Encoding = "windows-1252"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// Lookup resolves an encoding name given by a client.
+func Lookup(name string) (Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "utf-8", "utf8":
+		return UTF8, nil
+	case "utf-16le", "utf16le", "utf-16", "utf16", "ucs-2":
+		return UTF16LE, nil
+	case "utf-16be", "utf16be":
+		return UTF16BE, nil
+	case "windows-1252", "cp1252", "latin1", "iso-8859-1":
+		return Windows1252, nil
+	}
+	return "", fmt.Errorf("unsupported encoding %q", name)
+}
+
+// Detect guesses the encoding of the beginning of a file. A byte order mark
+// wins; otherwise UTF-16 is recognised by its zero bytes and anything that is
+// not valid UTF-8 is taken to be Windows-1252. bom reports whether a byte
+// order mark is present.
+func Detect(head []byte) (enc Encoding, bom bool) {
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		return UTF8, true
+	case bytes.HasPrefix(head, bomUTF16LE):
+		return UTF16LE, true
+	case bytes.HasPrefix(head, bomUTF16BE):
+		return UTF16BE, true
+	}
+
+	if len(head) >= 4 {
+		var evenZeros, oddZeros int
+		pairs := len(head) / 2
+		for i := 0; i+1 < len(head); i += 2 {
+			if head[i] == 0 {
+				evenZeros++
+			}
+			if head[i+1] == 0 {
+				oddZeros++
+			}
+		}
+		if oddZeros*3 > pairs && evenZeros*10 < pairs {
+			return UTF16LE, false
+		}
+		if evenZeros*3 > pairs && oddZeros*10 < pairs {
+			return UTF16BE, false
+		}
+	}
+
+	if utf8.Valid(trimIncompleteRune(head)) {
+		return UTF8, false
+	}
+	return Windows1252, false
+}
+
+// trimIncompleteRune drops a multi-byte sequence cut off at the end of a
+// sample so it does not make valid UTF-8 look invalid.
+func trimIncompleteRune(b []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(b); i++ {
+		c := b[len(b)-i]
+		if c < utf8.RuneSelf {
+			return b
+		}
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			return b
+		}
+	}
+	return b
+}
+
+// NewReader returns a reader producing UTF-8 from r in the given encoding.
+// A leading byte order mark is removed.
+func NewReader(r io.Reader, enc Encoding) io.Reader {
+	br := bufio.NewReader(r)
+	switch enc {
+	case UTF16LE:
+		skipPrefix(br, bomUTF16LE)
+		return &utf16Reader{src: br, order: littleEndian}
+	case UTF16BE:
+		skipPrefix(br, bomUTF16BE)
+		return &utf16Reader{src: br, order: bigEndian}
+	case Windows1252:
+		return &windows1252Reader{src: br}
+	default:
+		skipPrefix(br, bomUTF8)
+		return br
+	}
+}
+
+func skipPrefix(br *bufio.Reader, prefix []byte) {
+	if head, err := br.Peek(len(prefix)); err == nil && bytes.Equal(head, prefix) {
+		_, _ = br.Discard(len(prefix))
+	}
+}
+
+type byteOrder int
+
+const (
+	littleEndian byteOrder = iota
+	bigEndian
+)
+
+type utf16Reader struct {
+	src     *bufio.Reader
+	order   byteOrder
+	pending []byte
+}
+
+func (u *utf16Reader) readUnit() (uint16, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(u.src, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, fmt.Errorf("invalid utf-16 input: odd number of bytes")
+		}
+		return 0, err
+	}
+	if u.order == littleEndian {
+		return uint16(b[0]) | uint16(b[1])<<8, nil
+	}
+	return uint16(b[1]) | uint16(b[0])<<8, nil
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	n := copy(p, u.pending)
+	u.pending = u.pending[n:]
+
+	for n < len(p) {
+		unit, err := u.readUnit()
+		if err != nil {
+			if n > 0 && err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+
+		r := rune(unit)
+		if utf16.IsSurrogate(r) {
+			next, err := u.readUnit()
+			if err != nil && err != io.EOF {
+				return n, err
+			}
+			r = utf16.DecodeRune(r, rune(next))
+		}
+
+		var buf [utf8.UTFMax]byte
+		size := utf8.EncodeRune(buf[:], r)
+		copied := copy(p[n:], buf[:size])
+		n += copied
+		if copied < size {
+			u.pending = append(u.pending[:0], buf[copied:size]...)
+			break
+		}
+	}
+	return n, nil
+}
+
+// windows1252High maps the 0x80-0x9F range, where Windows-1252 differs
+// from Latin-1. Unassigned positions map to U+FFFD.
+var windows1252High = [32]rune{
+	0x20AC, 0xFFFD, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0xFFFD, 0x017D, 0xFFFD,
+	0xFFFD, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0xFFFD, 0x017E, 0x0178,
+}
+
+type windows1252Reader struct {
+	src     *bufio.Reader
+	pending []byte
+}
+
+func (w *windows1252Reader) Read(p []byte) (int, error) {
+	n := copy(p, w.pending)
+	w.pending = w.pending[n:]
+
+	for n < len(p) {
+		b, err := w.src.ReadByte()
+		if err != nil {
+			if n > 0 && err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+
+		r := rune(b)
+		if b >= 0x80 && b <= 0x9F {
+			r = windows1252High[b-0x80]
+		}
+
+		var buf [utf8.UTFMax]byte
+		size := utf8.EncodeRune(buf[:], r)
+		copied := copy(p[n:], buf[:size])
+		n += copied
+		if copied < size {
+			w.pending = append(w.pending[:0], buf[copied:size]...)
+			break
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,74 @@
+// bank-statement-viewer/pkg/textenc/textenc_test.go
+package textenc
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeUTF16LE(s string, bom bool) []byte {
+	var out []byte
+	if bom {
+		out = append(out, 0xFF, 0xFE)
+	}
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+func decode(t *testing.T, data []byte) (string, Encoding, bool) {
+	enc, bom := Detect(data)
+	out, err := io.ReadAll(NewReader(strings.NewReader(string(data)), enc))
+	assert.NoError(t, err)
+	return string(out), enc, bom
+}
+
+func TestDetectAndDecode_UTF8BOM(t *testing.T) {
+	out, enc, bom := decode(t, append([]byte{0xEF, 0xBB, 0xBF}, "timestamp;name"...))
+
+	assert.Equal(t, UTF8, enc)
+	assert.True(t, bom)
+	assert.Equal(t, "timestamp;name", out)
+}
+
+func TestDetectAndDecode_UTF16LE(t *testing.T) {
+	text := "timestamp,name\n1624507883,JOSÉ 😀\n"
+
+	out, enc, bom := decode(t, encodeUTF16LE(text, true))
+	assert.Equal(t, UTF16LE, enc)
+	assert.True(t, bom)
+	assert.Equal(t, text, out)
+
+	out, enc, bom = decode(t, encodeUTF16LE(text, false))
+	assert.Equal(t, UTF16LE, enc)
+	assert.False(t, bom)
+	assert.Equal(t, text, out)
+}
+
+func TestDetectAndDecode_Windows1252(t *testing.T) {
+	out, enc, _ := decode(t, []byte("caf\xe9 \x80 5"))
+
+	assert.Equal(t, Windows1252, enc)
+	assert.Equal(t, "café € 5", out)
+}
+
+func TestDetect_PlainUTF8(t *testing.T) {
+	enc, bom := Detect([]byte("name,amount\nJOSÉ,100\n"))
+
+	assert.Equal(t, UTF8, enc)
+	assert.False(t, bom)
+}
+
+func TestLookup(t *testing.T) {
+	enc, err := Lookup("CP1252")
+	assert.NoError(t, err)
+	assert.Equal(t, Windows1252, enc)
+
+	_, err = Lookup("ebcdic")
+	assert.Error(t, err)
+}
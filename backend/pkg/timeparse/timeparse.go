@@ -0,0 +1,129 @@
+package timeparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// LayoutUnix and LayoutUnixMilli can be used in place of a Go layout to
+	// force epoch seconds or milliseconds.
+	LayoutUnix      = "unix"
+	LayoutUnixMilli = "unix_ms"
+)
+
+// autoLayouts are tried in order when no layout is configured. Slashed
+// dates are read day first, as Indonesian bank exports write them.
+var autoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"02/01/2006",
+	"02-01-2006 15:04:05",
+	"02-01-2006",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"20060102",
+	"02 Jan 2006 15:04",
+	"02 Jan 2006",
+	"02-Jan-2006",
+	"02-Jan-06",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// Parse reads a timestamp using the given layouts, or detects the format
+// when none are given. Values without an explicit offset are interpreted in
+// loc; values with one keep it.
+func Parse(value string, layouts []string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if len(layouts) == 0 {
+		if t, ok := parseEpoch(value, loc); ok {
+			return t, nil
+		}
+		layouts = autoLayouts
+	}
+
+	for _, layout := range layouts {
+		switch layout {
+		case LayoutUnix:
+			if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return time.Unix(v, 0).In(loc), nil
+			}
+		case LayoutUnixMilli:
+			if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return time.UnixMilli(v).In(loc), nil
+			}
+		default:
+			if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+				return t, nil
+			}
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", value)
+}
+
+// parseEpoch guesses the unit of a numeric timestamp from its length:
+// seconds up to 11 digits, then milliseconds, microseconds and nanoseconds.
+// Eight digit values that form a valid YYYYMMDD date are left to the layouts.
+func parseEpoch(value string, loc *time.Location) (time.Time, bool) {
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	digits := len(strings.TrimLeft(value, "-+"))
+	if digits == 8 {
+		if _, err := time.Parse("20060102", value); err == nil {
+			return time.Time{}, false
+		}
+	}
+	switch {
+	case digits <= 11:
+		return time.Unix(v, 0).In(loc), true
+	case digits <= 14:
+		return time.UnixMilli(v).In(loc), true
+	case digits <= 17:
+		return time.UnixMicro(v).In(loc), true
+	default:
+		return time.Unix(0, v).In(loc), true
+	}
+}
+
+// LoadLocation resolves a timezone name such as "Asia/Jakarta" or a fixed
+// offset such as "+07:00".
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC, nil
+	}
+	if strings.HasPrefix(name, "+") || strings.HasPrefix(name, "-") {
+		t, err := time.Parse("-07:00", name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone offset %q", name)
+		}
+		_, offset := t.Zone()
+		return time.FixedZone(name, offset), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", name)
+	}
+	return loc, nil
+}
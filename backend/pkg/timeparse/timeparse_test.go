@@ -0,0 +1,53 @@
+// bank-statement-viewer/pkg/timeparse/timeparse_test.go
+package timeparse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_AutoDetect(t *testing.T) {
+	jakarta, err := LoadLocation("Asia/Jakarta")
+	assert.NoError(t, err)
+
+	cases := map[string]string{
+		"1624507883":                "2021-06-24T11:11:23+07:00",
+		"1624507883123":             "2021-06-24T11:11:23+07:00",
+		"2024-06-24":                "2024-06-24T00:00:00+07:00",
+		"24/06/2024 14:03":          "2024-06-24T14:03:00+07:00",
+		"2024-06-24T14:03:00Z":      "2024-06-24T14:03:00Z",
+		"2024-06-24T14:03:00+09:00": "2024-06-24T14:03:00+09:00",
+		"20240624":                  "2024-06-24T00:00:00+07:00",
+	}
+
+	for input, expected := range cases {
+		parsed, err := Parse(input, nil, jakarta)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, parsed.Format(time.RFC3339), input)
+	}
+}
+
+func TestParse_ConfiguredLayouts(t *testing.T) {
+	parsed, err := Parse("06/24/2024", []string{"01/02/2006"}, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-06-24", parsed.Format("2006-01-02"))
+
+	_, err = Parse("2024-06-24", []string{"01/02/2006"}, time.UTC)
+	assert.Error(t, err)
+
+	parsed, err = Parse("1624507883000", []string{LayoutUnixMilli}, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1624507883), parsed.Unix())
+}
+
+func TestLoadLocation(t *testing.T) {
+	loc, err := LoadLocation("+07:00")
+	assert.NoError(t, err)
+	_, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, loc).Zone()
+	assert.Equal(t, 7*3600, offset)
+
+	_, err = LoadLocation("Mars/Olympus")
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package txid
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+// Key is what identifies a transaction within its account: its bank
+// reference when it has one, otherwise its content. Status is left out so
+// that a pending entry keeps its identity once it settles.
+func Key(tx domain.Transaction) string {
+	if ref := strings.TrimSpace(tx.Reference); ref != "" {
+		return "ref\x00" + tx.AccountID + "\x00" + ref
+	}
+	return strings.Join([]string{
+		"tx",
+		tx.AccountID,
+		tx.Timestamp.UTC().Format(time.RFC3339Nano),
+		string(tx.Type),
+		strconv.FormatInt(tx.Amount.Minor, 10),
+		tx.Amount.Currency,
+		strings.TrimSpace(tx.Name),
+		strings.TrimSpace(tx.Description),
+	}, "\x00")
+}
+
+// Generator hands out IDs for the transactions of one upload. Rows with
+// the same key, such as two identical card payments in the same minute,
+// are told apart by the order they appear in, so re-uploading a file
+// yields the same IDs.
+type Generator struct {
+	seen map[string]int
+}
+
+func NewGenerator() *Generator {
+	return &Generator{seen: make(map[string]int)}
+}
+
+func (g *Generator) Next(tx domain.Transaction) string {
+	key := Key(tx)
+	g.seen[key]++
+
+	sum := sha256.Sum256([]byte(key))
+	id := hex.EncodeToString(sum[:10])
+	if n := g.seen[key]; n > 1 {
+		id += "-" + strconv.Itoa(n)
+	}
+	return id
+}
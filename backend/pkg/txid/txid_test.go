@@ -0,0 +1,67 @@
+// bank-statement-viewer/pkg/txid/txid_test.go
+package txid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func sample() domain.Transaction {
+	return domain.Transaction{
+		Timestamp: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC),
+		Name:      "Coffee",
+		Type:      domain.TypeDebit,
+		Amount:    domain.NewMoney(3500000, "IDR"),
+		Status:    domain.StatusPending,
+	}
+}
+
+func TestNext_Deterministic(t *testing.T) {
+	first := NewGenerator().Next(sample())
+	second := NewGenerator().Next(sample())
+
+	assert.Equal(t, first, second)
+	assert.Len(t, first, 20)
+}
+
+func TestNext_IgnoresStatusAndOffset(t *testing.T) {
+	settled := sample()
+	settled.Status = domain.StatusSuccess
+	settled.Timestamp = settled.Timestamp.In(time.FixedZone("WIB", 7*60*60))
+
+	assert.Equal(t, NewGenerator().Next(sample()), NewGenerator().Next(settled))
+}
+
+func TestNext_Reference(t *testing.T) {
+	a := sample()
+	a.Reference = "FIT-1"
+	b := sample()
+	b.Reference = "FIT-1"
+	b.Amount = domain.NewMoney(100, "IDR")
+	c := sample()
+	c.Reference = "FIT-2"
+
+	assert.Equal(t, NewGenerator().Next(a), NewGenerator().Next(b))
+	assert.NotEqual(t, NewGenerator().Next(a), NewGenerator().Next(c))
+	assert.NotEqual(t, NewGenerator().Next(sample()), NewGenerator().Next(a))
+}
+
+func TestNext_Occurrences(t *testing.T) {
+	g := NewGenerator()
+	first := g.Next(sample())
+	second := g.Next(sample())
+	third := g.Next(sample())
+
+	assert.Equal(t, first+"-2", second)
+	assert.Equal(t, first+"-3", third)
+}
+
+func TestNext_Account(t *testing.T) {
+	other := sample()
+	other.AccountID = "savings"
+
+	assert.NotEqual(t, NewGenerator().Next(sample()), NewGenerator().Next(other))
+}
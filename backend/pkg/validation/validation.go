@@ -0,0 +1,242 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/amount"
+)
+
+// Action is what happens to a transaction that breaks a rule.
+type Action string
+
+const (
+	// ActionReject drops the row like a parse error: the upload fails in
+	// strict mode and the row is reported in lenient mode.
+	ActionReject Action = "reject"
+	// ActionWarn keeps the row and lists it in the upload report.
+	ActionWarn Action = "warn"
+	// ActionTag keeps the row and adds a tag to it.
+	ActionTag Action = "tag"
+)
+
+const (
+	RuleNonNegativeAmount = "non_negative_amount"
+	RuleNonZeroAmount     = "non_zero_amount"
+	RuleTimestampWindow   = "timestamp_window"
+	RuleRequiredName      = "required_name"
+	RuleMaxAmount         = "max_amount"
+)
+
+// RuleConfig enables one built-in rule. Only the parameters of the chosen
+// rule are read.
+type RuleConfig struct {
+	Rule   string `json:"rule"`
+	Action Action `json:"action"`
+	// Tag is added by ActionTag; it defaults to the rule name.
+	Tag string `json:"tag,omitempty"`
+
+	// NotBefore (YYYY-MM-DD) and MaxFuture (a Go duration such as "24h")
+	// bound timestamp_window.
+	NotBefore string `json:"not_before,omitempty"`
+	MaxFuture string `json:"max_future,omitempty"`
+
+	// Max is the decimal limit of max_amount. With Currency set the rule
+	// only applies to that currency.
+	Max      string `json:"max,omitempty"`
+	Currency string `json:"currency,omitempty"`
+}
+
+type Config struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+// DefaultConfig is used when no rules file is configured.
+func DefaultConfig() Config {
+	return Config{Rules: []RuleConfig{
+		{Rule: RuleNonNegativeAmount, Action: ActionReject},
+		{Rule: RuleNonZeroAmount, Action: ActionWarn},
+		{Rule: RuleTimestampWindow, Action: ActionReject, NotBefore: "1990-01-01", MaxFuture: "24h"},
+		{Rule: RuleRequiredName, Action: ActionWarn},
+	}}
+}
+
+// Violation describes a broken rule.
+type Violation struct {
+	Rule   string
+	Field  string
+	Reason string
+}
+
+type rule struct {
+	name   string
+	action Action
+	tag    string
+	// check returns the violated field and reason, or an empty reason.
+	check func(tx *domain.Transaction, now time.Time) (field, reason string)
+}
+
+type Engine struct {
+	rules []rule
+	now   func() time.Time
+}
+
+func New(cfg Config) (*Engine, error) {
+	e := &Engine{now: time.Now}
+	for i, rc := range cfg.Rules {
+		r, err := buildRule(rc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid validation rule %d (%s): %w", i+1, rc.Rule, err)
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+// Load reads a JSON rules file.
+func Load(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read validation rules: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse validation rules %s: %w", path, err)
+	}
+	return New(cfg)
+}
+
+// Check runs every rule against tx. Tags are added to tx in place; the
+// first rejecting violation, if any, is returned together with all
+// warnings.
+func (e *Engine) Check(tx *domain.Transaction) (rejected *Violation, warnings []Violation) {
+	now := e.now()
+	for _, r := range e.rules {
+		field, reason := r.check(tx, now)
+		if reason == "" {
+			continue
+		}
+		v := Violation{Rule: r.name, Field: field, Reason: reason}
+		switch r.action {
+		case ActionReject:
+			if rejected == nil {
+				rejected = &v
+			}
+		case ActionWarn:
+			warnings = append(warnings, v)
+		case ActionTag:
+			tx.Tags = appendTag(tx.Tags, r.tag)
+		}
+	}
+	return rejected, warnings
+}
+
+func appendTag(tags []string, tag string) []string {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+func buildRule(rc RuleConfig) (rule, error) {
+	r := rule{name: rc.Rule, action: rc.Action, tag: rc.Tag}
+	switch rc.Action {
+	case ActionReject, ActionWarn, ActionTag:
+	default:
+		return rule{}, fmt.Errorf("unknown action %q, expected reject, warn or tag", rc.Action)
+	}
+	if r.tag == "" {
+		r.tag = rc.Rule
+	}
+
+	switch rc.Rule {
+	case RuleNonNegativeAmount:
+		r.check = func(tx *domain.Transaction, _ time.Time) (string, string) {
+			if tx.Amount.Minor < 0 {
+				return "amount", "amount is negative"
+			}
+			return "", ""
+		}
+	case RuleNonZeroAmount:
+		r.check = func(tx *domain.Transaction, _ time.Time) (string, string) {
+			if tx.Amount.Minor == 0 {
+				return "amount", "amount is zero"
+			}
+			return "", ""
+		}
+	case RuleRequiredName:
+		r.check = func(tx *domain.Transaction, _ time.Time) (string, string) {
+			if strings.TrimSpace(tx.Name) == "" {
+				return "name", "name is empty"
+			}
+			return "", ""
+		}
+	case RuleTimestampWindow:
+		check, err := timestampWindow(rc)
+		if err != nil {
+			return rule{}, err
+		}
+		r.check = check
+	case RuleMaxAmount:
+		check, err := maxAmount(rc)
+		if err != nil {
+			return rule{}, err
+		}
+		r.check = check
+	default:
+		return rule{}, fmt.Errorf("unknown rule %q", rc.Rule)
+	}
+	return r, nil
+}
+
+func timestampWindow(rc RuleConfig) (func(*domain.Transaction, time.Time) (string, string), error) {
+	var notBefore time.Time
+	if rc.NotBefore != "" {
+		t, err := time.Parse("2006-01-02", rc.NotBefore)
+		if err != nil {
+			return nil, fmt.Errorf("invalid not_before %q, expected YYYY-MM-DD", rc.NotBefore)
+		}
+		notBefore = t
+	}
+	maxFuture := time.Duration(-1)
+	if rc.MaxFuture != "" {
+		d, err := time.ParseDuration(rc.MaxFuture)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid max_future %q", rc.MaxFuture)
+		}
+		maxFuture = d
+	}
+	if notBefore.IsZero() && maxFuture < 0 {
+		return nil, fmt.Errorf("not_before or max_future is required")
+	}
+
+	return func(tx *domain.Transaction, now time.Time) (string, string) {
+		if !notBefore.IsZero() && tx.Timestamp.Before(notBefore) {
+			return "timestamp", fmt.Sprintf("timestamp is before %s", rc.NotBefore)
+		}
+		if maxFuture >= 0 && tx.Timestamp.After(now.Add(maxFuture)) {
+			return "timestamp", "timestamp is too far in the future"
+		}
+		return "", ""
+	}, nil
+}
+
+func maxAmount(rc RuleConfig) (func(*domain.Transaction, time.Time) (string, string), error) {
+	limit, err := amount.ParseLimit(rc.Max, rc.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max: %w", err)
+	}
+
+	return func(tx *domain.Transaction, _ time.Time) (string, string) {
+		if limit.Exceeded(tx.Amount) {
+			return "amount", fmt.Sprintf("amount exceeds %s", rc.Max)
+		}
+		return "", ""
+	}, nil
+}
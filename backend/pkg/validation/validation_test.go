@@ -0,0 +1,103 @@
+// bank-statement-viewer/pkg/validation/validation_test.go
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+
+func newEngine(t *testing.T, rules ...RuleConfig) *Engine {
+	e, err := New(Config{Rules: rules})
+	assert.NoError(t, err)
+	e.now = func() time.Time { return now }
+	return e
+}
+
+func TestCheck_Actions(t *testing.T) {
+	e := newEngine(t,
+		RuleConfig{Rule: RuleNonNegativeAmount, Action: ActionReject},
+		RuleConfig{Rule: RuleRequiredName, Action: ActionWarn},
+		RuleConfig{Rule: RuleMaxAmount, Action: ActionTag, Tag: "large", Max: "1000", Currency: "IDR"},
+	)
+
+	tx := domain.Transaction{Timestamp: now, Amount: domain.NewMoney(500000, "IDR")}
+	rejected, warnings := e.Check(&tx)
+
+	assert.Nil(t, rejected)
+	assert.Equal(t, []Violation{{Rule: RuleRequiredName, Field: "name", Reason: "name is empty"}}, warnings)
+	assert.Equal(t, []string{"large"}, tx.Tags)
+
+	tx = domain.Transaction{Timestamp: now, Name: "SHOP", Amount: domain.NewMoney(-100, "IDR")}
+	rejected, _ = e.Check(&tx)
+
+	assert.Equal(t, &Violation{Rule: RuleNonNegativeAmount, Field: "amount", Reason: "amount is negative"}, rejected)
+}
+
+func TestCheck_TimestampWindow(t *testing.T) {
+	e := newEngine(t, RuleConfig{Rule: RuleTimestampWindow, Action: ActionReject, NotBefore: "2000-01-01", MaxFuture: "24h"})
+
+	for _, tc := range []struct {
+		timestamp time.Time
+		reason    string
+	}{
+		{now, ""},
+		{now.Add(23 * time.Hour), ""},
+		{now.Add(25 * time.Hour), "timestamp is too far in the future"},
+		{time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), "timestamp is before 2000-01-01"},
+	} {
+		tx := domain.Transaction{Timestamp: tc.timestamp}
+		rejected, _ := e.Check(&tx)
+		if tc.reason == "" {
+			assert.Nil(t, rejected, tc.timestamp)
+			continue
+		}
+		assert.Equal(t, tc.reason, rejected.Reason, tc.timestamp)
+	}
+}
+
+func TestCheck_MaxAmountScalesToCurrency(t *testing.T) {
+	e := newEngine(t, RuleConfig{Rule: RuleMaxAmount, Action: ActionWarn, Max: "100"})
+
+	_, warnings := e.Check(&domain.Transaction{Amount: domain.NewMoney(10000, "USD")})
+	assert.Empty(t, warnings)
+
+	_, warnings = e.Check(&domain.Transaction{Amount: domain.NewMoney(10001, "USD")})
+	assert.Len(t, warnings, 1)
+
+	_, warnings = e.Check(&domain.Transaction{Amount: domain.NewMoney(101, "JPY")})
+	assert.Len(t, warnings, 1)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(Config{Rules: []RuleConfig{{Rule: RuleRequiredName, Action: "drop"}}})
+	assert.EqualError(t, err, `invalid validation rule 1 (required_name): unknown action "drop", expected reject, warn or tag`)
+
+	_, err = New(Config{Rules: []RuleConfig{{Rule: "iban", Action: ActionReject}}})
+	assert.EqualError(t, err, `invalid validation rule 1 (iban): unknown rule "iban"`)
+
+	_, err = New(Config{Rules: []RuleConfig{{Rule: RuleTimestampWindow, Action: ActionReject}}})
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	err := os.WriteFile(path, []byte(`{"rules": [{"rule": "required_name", "action": "tag", "tag": "unnamed"}]}`), 0o600)
+	assert.NoError(t, err)
+
+	e, err := Load(path)
+	assert.NoError(t, err)
+
+	tx := domain.Transaction{}
+	e.Check(&tx)
+	assert.Equal(t, []string{"unnamed"}, tx.Tags)
+
+	_, err = Load(filepath.Join("..", "..", "config", "validation.json"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+type accountRepository struct {
+	accounts map[string]domain.Account
+	mu       sync.RWMutex
+}
+
+func NewAccountRepository() domain.AccountRepository {
+	return &accountRepository{
+		accounts: make(map[string]domain.Account),
+	}
+}
+
+func (m *accountRepository) Create(ctx context.Context, account domain.Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.accounts[account.ID]; ok {
+		return domain.ErrAccountExists
+	}
+	m.accounts[account.ID] = account
+	return nil
+}
+
+func (m *accountRepository) Get(ctx context.Context, id string) (*domain.Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	account, ok := m.accounts[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &account, nil
+}
+
+func (m *accountRepository) List(ctx context.Context) ([]domain.Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accounts := make([]domain.Account, 0, len(m.accounts))
+	for _, account := range m.accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].ID < accounts[j].ID
+	})
+	return accounts, nil
+}
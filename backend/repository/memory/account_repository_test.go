@@ -0,0 +1,31 @@
+// bank-statement-viewer/repository/memory/account_repository_test.go
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountRepository(t *testing.T) {
+	repo := NewAccountRepository()
+	ctx := context.Background()
+
+	assert.NoError(t, repo.Create(ctx, domain.Account{ID: "savings", Name: "Savings", Currency: "IDR"}))
+	assert.NoError(t, repo.Create(ctx, domain.DefaultAccount()))
+	assert.ErrorIs(t, repo.Create(ctx, domain.Account{ID: "savings"}), domain.ErrAccountExists)
+
+	account, err := repo.Get(ctx, "savings")
+	assert.NoError(t, err)
+	assert.Equal(t, "Savings", account.Name)
+
+	_, err = repo.Get(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	accounts, err := repo.List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(accounts))
+	assert.Equal(t, domain.DefaultAccountID, accounts[0].ID)
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+type issueRepository struct {
+	records map[string]domain.IssueRecord
+	mu      sync.RWMutex
+}
+
+func NewIssueRepository() domain.IssueRepository {
+	return &issueRepository{
+		records: make(map[string]domain.IssueRecord),
+	}
+}
+
+func (m *issueRepository) Get(ctx context.Context, id string) (*domain.IssueRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	record, ok := m.records[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	record = cloneIssue(record)
+	return &record, nil
+}
+
+func (m *issueRepository) List(ctx context.Context) ([]domain.IssueRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	records := make([]domain.IssueRecord, 0, len(m.records))
+	for _, record := range m.records {
+		records = append(records, cloneIssue(record))
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+	return records, nil
+}
+
+func (m *issueRepository) Update(ctx context.Context, record domain.IssueRecord, fn func(*domain.IssueRecord) error) (*domain.IssueRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.records[record.ID]; ok {
+		record = stored
+	}
+	record = cloneIssue(record)
+	if err := fn(&record); err != nil {
+		return nil, err
+	}
+	m.records[record.ID] = cloneIssue(record)
+	return &record, nil
+}
+
+// cloneIssue copies the comments and history, so callers appending to them
+// never write to the stored record.
+func cloneIssue(record domain.IssueRecord) domain.IssueRecord {
+	record.Comments = slices.Clone(record.Comments)
+	record.History = slices.Clone(record.History)
+	return record
+}
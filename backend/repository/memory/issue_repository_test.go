@@ -0,0 +1,75 @@
+// bank-statement-viewer/repository/memory/issue_repository_test.go
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssueRepository(t *testing.T) {
+	repo := NewIssueRepository()
+	ctx := context.Background()
+
+	_, err := repo.Get(ctx, "tx1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	acknowledge := func(record *domain.IssueRecord) error {
+		if record.State != domain.IssueOpen {
+			return domain.ErrInvalidTransition
+		}
+		record.State = domain.IssueAcknowledged
+		record.History = append(record.History, domain.IssueEvent{Action: domain.IssueAcknowledge})
+		return nil
+	}
+	record, err := repo.Update(ctx, domain.IssueRecord{ID: "tx1", State: domain.IssueOpen}, acknowledge)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.IssueAcknowledged, record.State)
+	_, err = repo.Update(ctx, domain.IssueRecord{ID: "overdraft-tx0", State: domain.IssueOpen}, func(*domain.IssueRecord) error { return nil })
+	assert.NoError(t, err)
+
+	// The stored record is updated, not the one passed in, and nothing is
+	// stored when fn fails.
+	_, err = repo.Update(ctx, domain.IssueRecord{ID: "tx1", State: domain.IssueOpen}, acknowledge)
+	assert.ErrorIs(t, err, domain.ErrInvalidTransition)
+
+	stored, err := repo.Get(ctx, "tx1")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.IssueAcknowledged, stored.State)
+
+	// Changing the returned record does not change the stored one.
+	stored.History[0].Action = domain.IssueResolve
+	stored, _ = repo.Get(ctx, "tx1")
+	assert.Equal(t, domain.IssueAcknowledge, stored.History[0].Action)
+
+	records, err := repo.List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"overdraft-tx0", "tx1"}, []string{records[0].ID, records[1].ID})
+	assert.Equal(t, 1, len(records[1].History))
+}
+
+func TestIssueRepository_ConcurrentUpdates(t *testing.T) {
+	repo := NewIssueRepository()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Update(ctx, domain.IssueRecord{ID: "tx1"}, func(record *domain.IssueRecord) error {
+				record.History = append(record.History, domain.IssueEvent{Action: domain.IssueComment})
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	record, err := repo.Get(ctx, "tx1")
+	assert.NoError(t, err)
+	assert.Equal(t, 50, len(record.History), "no event is lost")
+}
@@ -2,19 +2,39 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"sync"
 
 	"github.com/novanm/bank-viewer/backend/domain"
 )
 
-type memoryRepository struct {
+// batch is one stored upload. settled lists the IDs of the transactions of
+// earlier uploads it updated.
+type batch struct {
+	upload       domain.Upload
 	transactions []domain.Transaction
-	mu           sync.RWMutex
+	settled      []string
+}
+
+type memoryRepository struct {
+	batches []*batch
+	// version is the last version handed out; versions are never reused.
+	version int
+	// byID maps transaction IDs to their batch and position. It is kept up
+	// to date by every change instead of being rebuilt.
+	byID map[string]position
+	mu   sync.RWMutex
+}
+
+type position struct {
+	batch *batch
+	index int
 }
 
 func NewMemoryRepository() domain.TransactionRepository {
 	return &memoryRepository{
-		transactions: make([]domain.Transaction, 0),
+		byID: make(map[string]position),
 	}
 }
 
@@ -22,15 +42,222 @@ func (m *memoryRepository) GetAll(ctx context.Context) ([]domain.Transaction, er
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	transactionsCopy := make([]domain.Transaction, len(m.transactions))
-	copy(transactionsCopy, m.transactions)
+	size := 0
+	for _, b := range m.batches {
+		size += len(b.transactions)
+	}
+	transactionsCopy := make([]domain.Transaction, 0, size)
+	for _, b := range m.batches {
+		transactionsCopy = append(transactionsCopy, b.transactions...)
+	}
 	return transactionsCopy, nil
 }
 
-func (m *memoryRepository) Store(ctx context.Context, transactions []domain.Transaction) error {
+func (m *memoryRepository) Store(ctx context.Context, upload domain.Upload, transactions []domain.Transaction) (*domain.Upload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.add(&batch{upload: upload, transactions: append([]domain.Transaction(nil), transactions...)}), nil
+}
+
+func (m *memoryRepository) GetByID(ctx context.Context, id string) (*domain.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.byID[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	tx := p.batch.transactions[p.index]
+	return &tx, nil
+}
+
+func (m *memoryRepository) UpdateTransaction(ctx context.Context, tx domain.Transaction) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.transactions = transactions
+	p, ok := m.byID[tx.ID]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	p.batch.transactions[p.index] = tx
+	return nil
+}
+
+func (m *memoryRepository) DeleteTransaction(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.byID[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	b := p.batch
+	b.transactions = append(b.transactions[:p.index:p.index], b.transactions[p.index+1:]...)
+	delete(m.byID, id)
+	m.index(b, p.index)
+	for _, other := range m.batches {
+		other.settled = slices.DeleteFunc(other.settled, func(s string) bool { return s == id })
+		other.upload.Rows = other.rows()
+	}
+	return nil
+}
+
+func (m *memoryRepository) ListUploads(ctx context.Context) ([]domain.Upload, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	uploads := make([]domain.Upload, 0, len(m.batches))
+	for _, b := range m.batches {
+		uploads = append(uploads, b.upload)
+	}
+	return uploads, nil
+}
+
+func (m *memoryRepository) DeleteUpload(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, b := range m.batches {
+		if b.upload.ID == id {
+			m.batches = append(m.batches[:i:i], m.batches[i+1:]...)
+			for _, settled := range b.settled {
+				if p, ok := m.byID[settled]; ok && p.batch != b {
+					revert(&p.batch.transactions[p.index], id)
+				}
+			}
+			// An earlier batch may hold a transaction with an ID of the
+			// removed one, and later batches may have settled its rows.
+			m.byID = make(map[string]position, len(m.byID))
+			for _, other := range m.batches {
+				m.index(other, 0)
+			}
+			for _, other := range m.batches {
+				other.settled = slices.DeleteFunc(other.settled, func(s string) bool {
+					_, ok := m.byID[s]
+					return !ok
+				})
+				other.upload.Rows = other.rows()
+			}
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+// add stores a batch under the next version; the caller holds the write
+// lock.
+func (m *memoryRepository) add(b *batch) *domain.Upload {
+	m.version++
+	b.upload.Version = m.version
+	b.upload.Rows = b.rows()
+	m.batches = append(m.batches, b)
+	m.index(b, 0)
+	upload := b.upload
+	return &upload
+}
+
+// rows counts the transactions an upload added or settled.
+func (b *batch) rows() int {
+	return len(b.transactions) + len(b.settled)
+}
+
+// revert drops the status changes an upload made to tx and restores the
+// status before them.
+func revert(tx *domain.Transaction, uploadID string) {
+	history := slices.DeleteFunc(slices.Clone(tx.StatusHistory), func(c domain.StatusChange) bool {
+		return c.UploadID == uploadID
+	})
+	if len(history) == len(tx.StatusHistory) || len(history) == 0 {
+		return
+	}
+	tx.StatusHistory = history
+	tx.Status = history[len(history)-1].Status
+}
+
+// index points the IDs of the transactions of b from position from on at
+// their current position; the caller holds the write lock.
+func (m *memoryRepository) index(b *batch, from int) {
+	for i := from; i < len(b.transactions); i++ {
+		if id := b.transactions[i].ID; id != "" {
+			m.byID[id] = position{batch: b, index: i}
+		}
+	}
+}
+
+func (m *memoryRepository) Begin(ctx context.Context, upload domain.Upload) (domain.TransactionWriter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &memoryWriter{repo: m, upload: upload, staged: make([]domain.Transaction, 0)}, nil
+}
+
+// memoryWriter stages an upload until it is committed. The repository lock
+// is only taken to add the staged batch, so reads are not blocked while an
+// upload is being parsed.
+type memoryWriter struct {
+	repo    *memoryRepository
+	upload  domain.Upload
+	staged  []domain.Transaction
+	updates []domain.Transaction
+	done    bool
+}
+
+var errWriterDone = errors.New("import already committed or rolled back")
+
+func (w *memoryWriter) Write(ctx context.Context, transactions []domain.Transaction) error {
+	if w.done {
+		return errWriterDone
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.staged = append(w.staged, transactions...)
+	return nil
+}
+
+func (w *memoryWriter) Update(ctx context.Context, tx domain.Transaction) error {
+	if w.done {
+		return errWriterDone
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.updates = append(w.updates, tx)
+	return nil
+}
+
+func (w *memoryWriter) Commit(ctx context.Context) (*domain.Upload, error) {
+	if w.done {
+		return nil, errWriterDone
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	w.done = true
+
+	w.repo.mu.Lock()
+	defer w.repo.mu.Unlock()
+
+	b := &batch{upload: w.upload, transactions: w.staged}
+	for _, tx := range w.updates {
+		if p, ok := w.repo.byID[tx.ID]; ok {
+			p.batch.transactions[p.index] = tx
+			b.settled = append(b.settled, tx.ID)
+		}
+	}
+	upload := w.repo.add(b)
+	w.staged = nil
+	w.updates = nil
+	return upload, nil
+}
+
+func (w *memoryWriter) Rollback(ctx context.Context) error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	w.staged = nil
+	w.updates = nil
 	return nil
 }
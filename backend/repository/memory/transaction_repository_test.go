@@ -15,13 +15,15 @@ func TestStoreAndGetAll_Success(t *testing.T) {
 
 	repo := NewMemoryRepository()
 	testData := []domain.Transaction{
-		{Name: "Test 1", Amount: 100},
-		{Name: "Test 2", Amount: 200},
+		{Name: "Test 1", Amount: domain.NewMoney(100, "IDR")},
+		{Name: "Test 2", Amount: domain.NewMoney(200, "IDR")},
 	}
 	ctx := context.Background()
 
-	err := repo.Store(ctx, testData)
+	upload, err := repo.Store(ctx, domain.Upload{ID: "u1", Filename: "january.csv"}, testData)
 	assert.NoError(t, err)
+	assert.Equal(t, 1, upload.Version)
+	assert.Equal(t, 2, upload.Rows)
 
 	data, err := repo.GetAll(ctx)
 	assert.NoError(t, err)
@@ -29,23 +31,24 @@ func TestStoreAndGetAll_Success(t *testing.T) {
 	assert.Equal(t, "Test 1", data[0].Name)
 
 	testData2 := []domain.Transaction{
-		{Name: "Test 3", Amount: 300},
+		{Name: "Test 3", Amount: domain.NewMoney(300, "IDR")},
 	}
-	err = repo.Store(ctx, testData2)
+	upload, err = repo.Store(ctx, domain.Upload{ID: "u2", Filename: "february.csv"}, testData2)
 	assert.NoError(t, err)
+	assert.Equal(t, 2, upload.Version)
 
 	data2, err := repo.GetAll(ctx)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, len(data2))
-	assert.Equal(t, "Test 3", data2[0].Name)
+	assert.Equal(t, 3, len(data2), "reads return the union of all uploads")
+	assert.Equal(t, "Test 3", data2[2].Name)
 }
 
 func TestRepository_Concurrency(t *testing.T) {
 	repo := NewMemoryRepository()
 	ctx := context.Background()
 
-	initialData := []domain.Transaction{{Name: "Initial", Amount: 1}}
-	err := repo.Store(ctx, initialData)
+	initialData := []domain.Transaction{{Name: "Initial", Amount: domain.NewMoney(1, "IDR")}}
+	_, err := repo.Store(ctx, domain.Upload{ID: "initial"}, initialData)
 	assert.NoError(t, err)
 
 	var wg sync.WaitGroup
@@ -54,8 +57,8 @@ func TestRepository_Concurrency(t *testing.T) {
 	go func() {
 		defer wg.Done()
 		time.Sleep(50 * time.Millisecond)
-		newData := []domain.Transaction{{Name: "New Data", Amount: 999}}
-		err := repo.Store(ctx, newData)
+		newData := []domain.Transaction{{Name: "New Data", Amount: domain.NewMoney(999, "IDR")}}
+		_, err := repo.Store(ctx, domain.Upload{ID: "new"}, newData)
 		assert.NoError(t, err)
 	}()
 
@@ -77,6 +80,225 @@ func TestRepository_Concurrency(t *testing.T) {
 
 	finalData, err := repo.GetAll(ctx)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, len(finalData))
-	assert.Equal(t, "New Data", finalData[0].Name)
+	assert.Equal(t, 2, len(finalData))
+	assert.Equal(t, "New Data", finalData[1].Name)
+}
+
+func TestWriter_CommitAddsUpload(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	_, err := repo.Store(ctx, domain.Upload{ID: "old"}, []domain.Transaction{{Name: "Old"}})
+	assert.NoError(t, err)
+
+	writer, err := repo.Begin(ctx, domain.Upload{ID: "new", Filename: "new.csv"})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(ctx, []domain.Transaction{{Name: "New 1"}}))
+	assert.NoError(t, writer.Write(ctx, []domain.Transaction{{Name: "New 2"}}))
+
+	data, _ := repo.GetAll(ctx)
+	assert.Equal(t, "Old", data[0].Name, "staged batches must not be visible before commit")
+
+	upload, err := writer.Commit(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Upload{ID: "new", Version: 2, Filename: "new.csv", Rows: 2}, *upload)
+
+	data, _ = repo.GetAll(ctx)
+	assert.Equal(t, 3, len(data))
+	assert.Equal(t, "Old", data[0].Name)
+	assert.Equal(t, "New 2", data[2].Name)
+
+	assert.Error(t, writer.Write(ctx, []domain.Transaction{{Name: "Late"}}))
+}
+
+func TestWriter_RollbackKeepsData(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, err := repo.Store(ctx, domain.Upload{ID: "old"}, []domain.Transaction{{Name: "Old"}})
+	assert.NoError(t, err)
+
+	writer, err := repo.Begin(ctx, domain.Upload{ID: "new"})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(ctx, []domain.Transaction{{Name: "New"}}))
+
+	cancel()
+	assert.ErrorIs(t, writer.Write(ctx, []domain.Transaction{{Name: "New"}}), context.Canceled)
+	assert.NoError(t, writer.Rollback(ctx))
+
+	data, _ := repo.GetAll(context.Background())
+	assert.Equal(t, 1, len(data))
+	assert.Equal(t, "Old", data[0].Name)
+
+	uploads, _ := repo.ListUploads(context.Background())
+	assert.Equal(t, 1, len(uploads))
+}
+
+func TestGetByID(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	_, err := repo.Store(ctx, domain.Upload{ID: "old"}, []domain.Transaction{{ID: "a", Name: "Old"}, {ID: "c", Name: "Old 2"}})
+	assert.NoError(t, err)
+
+	writer, err := repo.Begin(ctx, domain.Upload{ID: "new"})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(ctx, []domain.Transaction{{ID: "b", Name: "New 1"}, {ID: "c", Name: "New 2"}}))
+
+	_, err = repo.GetByID(ctx, "b")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	_, err = writer.Commit(ctx)
+	assert.NoError(t, err)
+
+	tx, err := repo.GetByID(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, "New 2", tx.Name, "the latest upload wins")
+
+	tx, err = repo.GetByID(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "Old", tx.Name)
+}
+
+func TestDeleteUpload(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	for _, id := range []string{"january", "february", "march"} {
+		_, err := repo.Store(ctx, domain.Upload{ID: id}, []domain.Transaction{{ID: id, Name: id}})
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, repo.DeleteUpload(ctx, "february"))
+	assert.ErrorIs(t, repo.DeleteUpload(ctx, "february"), domain.ErrNotFound)
+
+	data, _ := repo.GetAll(ctx)
+	assert.Equal(t, []string{"january", "march"}, []string{data[0].Name, data[1].Name})
+	_, err := repo.GetByID(ctx, "february")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	tx, err := repo.GetByID(ctx, "march")
+	assert.NoError(t, err)
+	assert.Equal(t, "march", tx.Name)
+
+	// Versions are not reused after a delete.
+	upload, err := repo.Store(ctx, domain.Upload{ID: "april"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, upload.Version)
+
+	uploads, _ := repo.ListUploads(ctx)
+	assert.Equal(t, []int{1, 3, 4}, []int{uploads[0].Version, uploads[1].Version, uploads[2].Version})
+}
+
+func TestDeleteUpload_ReindexesEarlierVersion(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	_, err := repo.Store(ctx, domain.Upload{ID: "june"}, []domain.Transaction{{ID: "a", Name: "first"}, {ID: "b", Status: domain.StatusPending}})
+	assert.NoError(t, err)
+	_, err = repo.Store(ctx, domain.Upload{ID: "july"}, []domain.Transaction{{ID: "a", Name: "second"}})
+	assert.NoError(t, err)
+
+	// August settles June's pending row.
+	writer, _ := repo.Begin(ctx, domain.Upload{ID: "august"})
+	assert.NoError(t, writer.Update(ctx, domain.Transaction{ID: "b", Status: domain.StatusSuccess}))
+	_, err = writer.Commit(ctx)
+	assert.NoError(t, err)
+
+	tx, _ := repo.GetByID(ctx, "a")
+	assert.Equal(t, "second", tx.Name, "the latest version wins")
+
+	assert.NoError(t, repo.DeleteUpload(ctx, "july"))
+	tx, err = repo.GetByID(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "first", tx.Name)
+
+	assert.NoError(t, repo.DeleteUpload(ctx, "june"))
+	uploads, _ := repo.ListUploads(ctx)
+	assert.Equal(t, 1, len(uploads))
+	assert.Equal(t, 0, uploads[0].Rows, "the row August settled is gone")
+}
+
+func TestUpdateAndDeleteTransaction(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	_, err := repo.Store(ctx, domain.Upload{ID: "june"}, []domain.Transaction{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.UpdateTransaction(ctx, domain.Transaction{ID: "b", Name: "B2"}))
+	assert.ErrorIs(t, repo.UpdateTransaction(ctx, domain.Transaction{ID: "c"}), domain.ErrNotFound)
+	tx, _ := repo.GetByID(ctx, "b")
+	assert.Equal(t, "B2", tx.Name)
+
+	assert.NoError(t, repo.DeleteTransaction(ctx, "a"))
+	assert.ErrorIs(t, repo.DeleteTransaction(ctx, "a"), domain.ErrNotFound)
+
+	data, _ := repo.GetAll(ctx)
+	assert.Equal(t, 1, len(data))
+	assert.Equal(t, "B2", data[0].Name)
+	tx, err = repo.GetByID(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, "B2", tx.Name)
+
+	// The index follows the rows that moved up.
+	assert.NoError(t, repo.UpdateTransaction(ctx, domain.Transaction{ID: "b", Name: "B3"}))
+	data[0].Name = "changed by caller"
+	data, _ = repo.GetAll(ctx)
+	assert.Equal(t, "B3", data[0].Name)
+
+	uploads, _ := repo.ListUploads(ctx)
+	assert.Equal(t, 1, uploads[0].Rows)
+}
+
+func TestWriter_UpdateAppliedOnCommit(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	_, err := repo.Store(ctx, domain.Upload{ID: "june"}, []domain.Transaction{{ID: "a", Status: domain.StatusPending}})
+	assert.NoError(t, err)
+
+	writer, err := repo.Begin(ctx, domain.Upload{ID: "july"})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Update(ctx, domain.Transaction{ID: "a", Status: domain.StatusSuccess}))
+	assert.NoError(t, writer.Update(ctx, domain.Transaction{ID: "deleted", Status: domain.StatusSuccess}))
+
+	tx, _ := repo.GetByID(ctx, "a")
+	assert.Equal(t, domain.StatusPending, tx.Status, "updates must not be visible before commit")
+
+	_, err = writer.Commit(ctx)
+	assert.NoError(t, err)
+
+	tx, _ = repo.GetByID(ctx, "a")
+	assert.Equal(t, domain.StatusSuccess, tx.Status)
+	data, _ := repo.GetAll(ctx)
+	assert.Equal(t, 1, len(data))
+}
+
+func TestDeleteUpload_RevertsSettlement(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	pending := domain.Transaction{ID: "a", Status: domain.StatusPending,
+		StatusHistory: []domain.StatusChange{{Status: domain.StatusPending, UploadID: "june"}}}
+	_, err := repo.Store(ctx, domain.Upload{ID: "june"}, []domain.Transaction{pending})
+	assert.NoError(t, err)
+
+	settled := pending
+	settled.Status = domain.StatusSuccess
+	settled.StatusHistory = append(settled.StatusHistory, domain.StatusChange{Status: domain.StatusSuccess, UploadID: "july"})
+	writer, err := repo.Begin(ctx, domain.Upload{ID: "july"})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Update(ctx, settled))
+	upload, err := writer.Commit(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, upload.Rows, "the settled row belongs to the new upload")
+
+	uploads, _ := repo.ListUploads(ctx)
+	assert.Equal(t, []int{1, 1}, []int{uploads[0].Rows, uploads[1].Rows})
+
+	assert.NoError(t, repo.DeleteUpload(ctx, "july"))
+	tx, err := repo.GetByID(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusPending, tx.Status)
+	assert.Equal(t, pending.StatusHistory, tx.StatusHistory)
 }
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+)
+
+type AccountService struct {
+	repo domain.AccountRepository
+}
+
+func NewAccountService(repo domain.AccountRepository) *AccountService {
+	return &AccountService{repo: repo}
+}
+
+var accountIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// CreateAccount stores a new account. Without an ID one is generated; the
+// currency defaults to domain.DefaultCurrency.
+func (s *AccountService) CreateAccount(ctx context.Context, account domain.Account) (*domain.Account, error) {
+	account.ID = strings.ToLower(strings.TrimSpace(account.ID))
+	if account.ID == "" {
+		account.ID = newID()
+	}
+	if !accountIDPattern.MatchString(account.ID) {
+		return nil, fmt.Errorf("invalid account id %q, expected lowercase letters, digits, '-' or '_'", account.ID)
+	}
+
+	account.Name = strings.TrimSpace(account.Name)
+	if account.Name == "" {
+		return nil, fmt.Errorf("account name is required")
+	}
+
+	account.Currency = strings.ToUpper(strings.TrimSpace(account.Currency))
+	if account.Currency == "" {
+		account.Currency = domain.DefaultCurrency
+	}
+	if len(account.Currency) != 3 {
+		return nil, fmt.Errorf("invalid currency %q, expected a 3-letter ISO 4217 code", account.Currency)
+	}
+
+	if err := s.repo.Create(ctx, account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (s *AccountService) GetAccount(ctx context.Context, id string) (*domain.Account, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *AccountService) ListAccounts(ctx context.Context) ([]domain.Account, error) {
+	return s.repo.List(ctx)
+}
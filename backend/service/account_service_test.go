@@ -0,0 +1,78 @@
+// bank-statement-viewer/service/account_service_test.go
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountRepository) Create(ctx context.Context, account domain.Account) error {
+	args := m.Called(ctx, account)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) Get(ctx context.Context, id string) (*domain.Account, error) {
+	args := m.Called(ctx, id)
+	account, _ := args.Get(0).(*domain.Account)
+	return account, args.Error(1)
+}
+
+func (m *MockAccountRepository) List(ctx context.Context) ([]domain.Account, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Account), args.Error(1)
+}
+
+func TestCreateAccount_Defaults(t *testing.T) {
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("Create", mock.Anything, domain.Account{ID: "bca-main", Name: "Main", Bank: "BCA", Currency: "IDR"}).Return(nil)
+
+	s := NewAccountService(mockRepo)
+
+	account, err := s.CreateAccount(context.Background(), domain.Account{ID: " BCA-Main ", Name: "Main", Bank: "BCA"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "bca-main", account.ID)
+	assert.Equal(t, "IDR", account.Currency)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateAccount_GeneratesID(t *testing.T) {
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	s := NewAccountService(mockRepo)
+
+	account, err := s.CreateAccount(context.Background(), domain.Account{Name: "Savings", Currency: "usd"})
+
+	assert.NoError(t, err)
+	assert.Len(t, account.ID, 16)
+	assert.Equal(t, "USD", account.Currency)
+}
+
+func TestCreateAccount_Invalid(t *testing.T) {
+	mockRepo := new(MockAccountRepository)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(domain.ErrAccountExists)
+
+	s := NewAccountService(mockRepo)
+	ctx := context.Background()
+
+	_, err := s.CreateAccount(ctx, domain.Account{ID: "main"})
+	assert.ErrorContains(t, err, "name is required")
+
+	_, err = s.CreateAccount(ctx, domain.Account{ID: "a/b", Name: "Main"})
+	assert.ErrorContains(t, err, "invalid account id")
+
+	_, err = s.CreateAccount(ctx, domain.Account{Name: "Main", Currency: "RUPIAH"})
+	assert.ErrorContains(t, err, "invalid currency")
+
+	_, err = s.CreateAccount(ctx, domain.Account{ID: "main", Name: "Main"})
+	assert.ErrorIs(t, err, domain.ErrAccountExists)
+}
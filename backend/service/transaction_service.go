@@ -1,97 +1,1234 @@
 package service
 
 import (
+	"bufio"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"math"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/archive"
 	"github.com/novanm/bank-viewer/backend/pkg/csvparser"
+	"github.com/novanm/bank-viewer/backend/pkg/dedup"
+	"github.com/novanm/bank-viewer/backend/pkg/issuerule"
+	"github.com/novanm/bank-viewer/backend/pkg/registry"
+	"github.com/novanm/bank-viewer/backend/pkg/textenc"
+	"github.com/novanm/bank-viewer/backend/pkg/txid"
+	"github.com/novanm/bank-viewer/backend/pkg/validation"
 )
 
 type TransactionService struct {
-	repo domain.TransactionRepository
+	repo      domain.TransactionRepository
+	location  *time.Location
+	parsers   *registry.Registry
+	batchSize int
+	validator *validation.Engine
+	accounts  domain.AccountRepository
+	issues    *issuerule.Engine
+	issueRepo domain.IssueRepository
+	// uploading holds a *sync.Mutex per account, so uploads to one account
+	// are checked against each other's rows rather than the same snapshot.
+	uploading sync.Map
 }
 
-func NewTransactionService(repo domain.TransactionRepository) *TransactionService {
-	return &TransactionService{
-		repo: repo,
+type Option func(*TransactionService)
+
+// WithLocation sets the statement timezone used for timestamps that carry
+// no offset when the upload does not choose one.
+func WithLocation(loc *time.Location) Option {
+	return func(s *TransactionService) {
+		s.location = loc
+	}
+}
+
+// WithParsers sets the statement parsers uploads are dispatched to.
+func WithParsers(parsers *registry.Registry) Option {
+	return func(s *TransactionService) {
+		s.parsers = parsers
+	}
+}
+
+// WithBatchSize sets how many parsed transactions are buffered before they
+// are written to the repository.
+func WithBatchSize(n int) Option {
+	return func(s *TransactionService) {
+		if n > 0 {
+			s.batchSize = n
+		}
+	}
+}
+
+// WithValidator runs the rules of v on every parsed transaction before it
+// is stored.
+func WithValidator(v *validation.Engine) Option {
+	return func(s *TransactionService) {
+		s.validator = v
+	}
+}
+
+// WithAccounts makes uploads and queries check that the account they name
+// exists; uploads also default to the account's currency.
+func WithAccounts(accounts domain.AccountRepository) Option {
+	return func(s *TransactionService) {
+		s.accounts = accounts
+	}
+}
+
+// WithIssueRules sets the rules that decide which transactions are listed
+// as issues.
+func WithIssueRules(e *issuerule.Engine) Option {
+	return func(s *TransactionService) {
+		s.issues = e
+	}
+}
+
+// WithIssueTracking stores the work on issues in repo. Without it every
+// issue is open and cannot be updated.
+func WithIssueTracking(repo domain.IssueRepository) Option {
+	return func(s *TransactionService) {
+		s.issueRepo = repo
+	}
+}
+
+// NewTransactionService reads CSV only unless WithParsers supplies a
+// registry with more formats, and lists failed and pending transactions as
+// issues unless WithIssueRules supplies other rules.
+func NewTransactionService(repo domain.TransactionRepository, opts ...Option) *TransactionService {
+	csv := csvparser.New()
+	parsers := registry.New()
+	parsers.Register(csv)
+	parsers.SetFallback(csv)
+
+	s := &TransactionService{
+		repo:      repo,
+		location:  time.UTC,
+		parsers:   parsers,
+		batchSize: defaultBatchSize,
+		issues:    issuerule.Default(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-func (s *TransactionService) ProcessUpload(ctx context.Context, fileReader io.Reader) error {
-	transactions, err := csvparser.Parse(fileReader)
+func (s *TransactionService) ProcessUpload(ctx context.Context, fileReader io.Reader, opts domain.UploadOptions) (*domain.UploadResponse, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = domain.ModeStrict
+	}
+
+	accountID := opts.AccountID
+	if accountID == "" {
+		accountID = domain.DefaultAccountID
+	}
+	account, err := s.account(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Currency == "" && account != nil {
+		opts.Currency = account.Currency
+	}
+
+	policy := opts.Duplicates
+	if policy == "" {
+		policy = domain.DuplicateFlag
+	}
+
+	response := &domain.UploadResponse{
+		UploadID:   newID(),
+		AccountID:  accountID,
+		Mode:       mode,
+		Files:      make([]domain.FileResult, 0, 1),
+		Duplicates: domain.DuplicateSummary{Policy: policy},
+	}
+
+	var forced domain.StatementParser
+	if opts.Format != "" {
+		p, err := s.parsers.Lookup(opts.Format)
+		if err != nil {
+			return nil, err
+		}
+		forced = p
+	}
+
+	// The MIME type of the upload describes the archive, not its contents.
+	upload := bufio.NewReader(fileReader)
+	if head, _ := upload.Peek(4); archive.Detect(head) != archive.KindNone {
+		opts.MIMEType = ""
+	}
+
+	// Settled rows are first matched to the pending rows of earlier uploads,
+	// then every row is checked for duplicates.
+	unlock := s.lockAccount(accountID)
+	defer unlock()
+	existing, err := s.storedTransactions(ctx, accountID)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	pending := make(map[string]domain.Transaction)
+	open := make([]domain.Transaction, 0)
+	for _, tx := range existing {
+		if tx.Status == domain.StatusPending {
+			pending[tx.ID] = tx
+			open = append(open, tx)
+		}
+	}
+	settlements := dedup.NewIndex(open, dedup.SettlementWindow)
+	duplicates := dedup.NewIndex(existing, dedup.DefaultWindow)
 
-	err = s.repo.Store(ctx, transactions)
+	createdAt := time.Now()
+	writer, err := s.repo.Begin(ctx, domain.Upload{
+		ID:        response.UploadID,
+		AccountID: accountID,
+		Filename:  opts.Filename,
+		CreatedAt: createdAt,
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = writer.Rollback(ctx)
+		}
+	}()
 
-	return nil
+	// Transactions go to the repository in fixed-size batches as they are
+	// parsed, so an upload is never held in memory as a whole. Nothing is
+	// visible until the commit, so an archive is imported completely or not
+	// at all, as one upload that can be deleted again. Checking the context
+	// on every row stops the work as soon as the client goes away.
+	ids := txid.NewGenerator()
+	var file string
+	batch := make([]domain.Transaction, 0, s.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := writer.Write(ctx, batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+	emit := func(tx domain.Transaction, line int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tx.AccountID = accountID
+		tx.ID = ids.Next(tx)
+		tx.Source = &domain.TransactionSource{UploadID: response.UploadID, File: file, Line: line}
+		tx.StatusHistory = []domain.StatusChange{{Status: tx.Status, At: createdAt, UploadID: response.UploadID}}
+		if tx.Status != domain.StatusPending {
+			if match := settlements.Match(tx); match != nil {
+				original := pending[match.Of]
+				settle(&original, tx, createdAt, response.UploadID)
+				response.Reconciled++
+				return writer.Update(ctx, original)
+			}
+		}
+		if match := duplicates.Match(tx); match != nil {
+			if policy == domain.DuplicateSkip {
+				response.Duplicates.Skipped++
+				return nil
+			}
+			// The original keeps the ID; a flagged or kept copy gets its own.
+			if match.Rule == dedup.RuleID {
+				tx.ID += duplicateIDSuffix + response.UploadID
+			}
+			if policy == domain.DuplicateFlag {
+				tx.Duplicate = match
+				response.Duplicates.Flagged++
+			}
+		}
+		batch = append(batch, tx)
+		if len(batch) >= s.batchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	kind, err := archive.Walk(upload, opts.Filename, maxDecompressedSize, func(name string, r io.Reader) error {
+		file = name
+		result, err := s.parseFile(r, name, forced, opts, mode, emit)
+		if err != nil {
+			return err
+		}
+		result.Name = name
+		response.Files = append(response.Files, *result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	response.Archive = string(kind)
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	stored, err := writer.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	committed = true
+	response.Version = stored.Version
+
+	return response, nil
 }
 
-func (s *TransactionService) GetBalance(ctx context.Context) (*domain.BalanceResponse, error) {
+func (s *TransactionService) parseFile(fileReader io.Reader, name string, parser domain.StatementParser, opts domain.UploadOptions, mode domain.UploadMode, emit domain.EmitFunc) (*domain.FileResult, error) {
+	raw := bufio.NewReaderSize(fileReader, sniffSize)
+	head, err := peek(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding, bom := textenc.Detect(head)
+	if opts.Encoding != "" {
+		encoding, err = textenc.Lookup(opts.Encoding)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buffered := bufio.NewReaderSize(textenc.NewReader(raw, encoding), sniffSize)
+	head, err = peek(buffered)
+	if err != nil {
+		return nil, err
+	}
+
+	if parser == nil {
+		parser, err = s.parsers.Resolve(domain.SourceInfo{Filename: name, MIMEType: opts.MIMEType, Head: head})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	loc := opts.Location
+	if loc == nil {
+		loc = s.location
+	}
+
+	var warnings []domain.RowError
+	if s.validator != nil {
+		next := emit
+		emit = func(tx domain.Transaction, line int) error {
+			rejected, found := s.validator.Check(&tx)
+			if rejected != nil {
+				return &domain.RowError{Line: line, Field: rejected.Field, Reason: rejected.Reason, Rule: rejected.Rule}
+			}
+			for _, w := range found {
+				warnings = append(warnings, domain.RowError{Line: line, Field: w.Field, Reason: w.Reason, Rule: w.Rule})
+			}
+			return next(tx, line)
+		}
+	}
+
+	result, err := parser.Parse(buffered, domain.ParseOptions{
+		Lenient:     mode == domain.ModeLenient,
+		Currency:    opts.Currency,
+		Location:    loc,
+		TimeLayouts: opts.TimeLayouts,
+		Delimiter:   opts.Delimiter,
+	}, emit)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Report != nil {
+		result.Report.Warnings = warnings
+	}
+
+	detected := domain.DetectedSettings{Encoding: string(encoding), BOM: bom}
+	if result.Delimiter != 0 {
+		detected.Delimiter = string(result.Delimiter)
+	}
+
+	return &domain.FileResult{
+		Format:     parser.Format(),
+		Detected:   detected,
+		Report:     result.Report,
+		Statements: result.Statements,
+	}, nil
+}
+
+// defaultBatchSize is how many transactions are written to the repository
+// at a time.
+const defaultBatchSize = 1000
+
+// maxDecompressedSize bounds how much data a compressed upload may expand
+// to, across all files of an archive.
+const maxDecompressedSize = 500 * 1024 * 1024 // 500 MB
+
+// sniffSize is how much of an upload is inspected to pick the encoding
+// and the parser.
+const sniffSize = 4096
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// storedTransactions returns the transactions of an account that uploads
+// are checked against, leaving out flagged duplicates.
+func (s *TransactionService) storedTransactions(ctx context.Context, accountID string) ([]domain.Transaction, error) {
+	transactions, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	own := make([]domain.Transaction, 0)
+	for _, tx := range transactions {
+		if tx.AccountID == accountID && tx.Duplicate == nil {
+			own = append(own, tx)
+		}
+	}
+	return own, nil
+}
+
+// lockAccount serialises uploads to an account from loading its stored
+// transactions until the upload is committed.
+func (s *TransactionService) lockAccount(accountID string) func() {
+	mu, _ := s.uploading.LoadOrStore(accountID, new(sync.Mutex))
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// settle moves a pending transaction forward to the status of its settled
+// counterpart, keeping its ID and source.
+func settle(pending *domain.Transaction, settled domain.Transaction, at time.Time, uploadID string) {
+	pending.Status = settled.Status
+	pending.StatusHistory = append(slices.Clone(pending.StatusHistory), domain.StatusChange{
+		Status: settled.Status, At: at, UploadID: uploadID,
+		TransactionID: settled.ID, Timestamp: settled.Timestamp,
+	})
+	mergeDuplicate(pending, settled)
+}
+
+// account returns the account with the given ID, or nil when the service
+// does not track accounts.
+func (s *TransactionService) account(ctx context.Context, id string) (*domain.Account, error) {
+	if s.accounts == nil || id == "" {
+		return nil, nil
+	}
+	account, err := s.accounts.Get(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("unknown account %q: %w", id, err)
+	}
+	return account, err
+}
+
+func peek(r *bufio.Reader) ([]byte, error) {
+	head, err := r.Peek(sniffSize)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	return head, nil
+}
+
+func (s *TransactionService) GetTransaction(ctx context.Context, id string) (*domain.Transaction, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// ListUploads returns the stored uploads, newest first.
+func (s *TransactionService) ListUploads(ctx context.Context, accountID string) ([]domain.Upload, error) {
+	if _, err := s.account(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	all, err := s.repo.ListUploads(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]domain.Upload, 0, len(all))
+	for _, upload := range all {
+		if accountID == "" || upload.AccountID == accountID {
+			uploads = append(uploads, upload)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].Version > uploads[j].Version
+	})
+	return uploads, nil
+}
+
+// DeleteUpload removes an upload and the transactions it imported; other
+// uploads are not affected.
+func (s *TransactionService) DeleteUpload(ctx context.Context, id string) error {
+	return s.repo.DeleteUpload(ctx, id)
+}
+
+// ListDuplicates returns the flagged duplicates awaiting review with the
+// transactions they matched, oldest first.
+func (s *TransactionService) ListDuplicates(ctx context.Context, accountID string) ([]domain.DuplicateReview, error) {
+	if _, err := s.account(ctx, accountID); err != nil {
+		return nil, err
+	}
+
 	transactions, err := s.repo.GetAll(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var totalBalance int64
+	reviews := make([]domain.DuplicateReview, 0)
 	for _, tx := range transactions {
-		if tx.Status == domain.StatusSuccess {
-			switch tx.Type {
-			case domain.TypeCredit:
-				totalBalance += tx.Amount
-			case domain.TypeDebit:
-				totalBalance -= tx.Amount
-			}
+		if tx.Duplicate == nil || (accountID != "" && tx.AccountID != accountID) {
+			continue
+		}
+		original, err := s.repo.GetByID(ctx, tx.Duplicate.Of)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
+		reviews = append(reviews, domain.DuplicateReview{Duplicate: tx, Original: original})
+	}
+	sort.SliceStable(reviews, func(i, j int) bool {
+		return reviews[i].Duplicate.Timestamp.Before(reviews[j].Duplicate.Timestamp)
+	})
+	return reviews, nil
+}
+
+// ResolveDuplicate merges a flagged duplicate into its original, which is
+// returned, or keeps it as a transaction of its own.
+func (s *TransactionService) ResolveDuplicate(ctx context.Context, id string, action domain.DuplicateAction) (*domain.Transaction, error) {
+	tx, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Duplicate == nil {
+		return nil, domain.ErrNotDuplicate
+	}
+
+	switch action {
+	case domain.DuplicateKeepBoth:
+		tx.Duplicate = nil
+		if err := s.repo.UpdateTransaction(ctx, *tx); err != nil {
+			return nil, err
+		}
+		return tx, nil
+	case domain.DuplicateMerge:
+		original, err := s.repo.GetByID(ctx, tx.Duplicate.Of)
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("original transaction %q no longer exists, keep the duplicate instead: %w", tx.Duplicate.Of, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		mergeDuplicate(original, *tx)
+		if err := s.repo.UpdateTransaction(ctx, *original); err != nil {
+			return nil, err
+		}
+		if err := s.repo.DeleteTransaction(ctx, tx.ID); err != nil {
+			return nil, err
+		}
+		return original, nil
+	default:
+		return nil, fmt.Errorf("unknown duplicate action %q, expected merge or keep", action)
+	}
+}
+
+// mergeDuplicate copies the details original lacks from its duplicate.
+func mergeDuplicate(original *domain.Transaction, duplicate domain.Transaction) {
+	if original.Reference == "" {
+		original.Reference = duplicate.Reference
+	}
+	if original.Description == "" {
+		original.Description = duplicate.Description
+	}
+	// The map and slice are shared with the stored transaction.
+	metadata := maps.Clone(original.Metadata)
+	for k, v := range duplicate.Metadata {
+		if _, ok := metadata[k]; ok {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[k] = v
+	}
+	original.Metadata = metadata
+	original.Tags = slices.Clone(original.Tags)
+	for _, tag := range duplicate.Tags {
+		if !slices.Contains(original.Tags, tag) {
+			original.Tags = append(original.Tags, tag)
+		}
+	}
+}
+
+func (s *TransactionService) GetBalance(ctx context.Context, accountID string, at time.Time) (*domain.BalanceResponse, error) {
+	if _, err := s.account(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	all, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]domain.Transaction, 0, len(all))
+	for _, tx := range all {
+		if accountID != "" && tx.AccountID != accountID {
+			continue
+		}
+		if !at.IsZero() && tx.Timestamp.After(at) {
+			continue
 		}
+		transactions = append(transactions, tx)
+	}
+	var cutoff *time.Time
+	if !at.IsZero() {
+		cutoff = &at
 	}
 
+	if accountID != "" {
+		return &domain.BalanceResponse{
+			AccountID: accountID,
+			At:        cutoff,
+			Balances:  balances(transactions),
+		}, nil
+	}
+
+	byAccount := make(map[string][]domain.Transaction)
+	for _, tx := range transactions {
+		byAccount[tx.AccountID] = append(byAccount[tx.AccountID], tx)
+	}
+	accounts := make([]domain.AccountBalance, 0, len(byAccount))
+	for id, txs := range byAccount {
+		accounts = append(accounts, domain.AccountBalance{AccountID: id, Balances: balances(txs)})
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].AccountID < accounts[j].AccountID
+	})
+
 	return &domain.BalanceResponse{
-		TotalBalance: totalBalance,
+		At:       cutoff,
+		Balances: balances(transactions),
+		Accounts: accounts,
 	}, nil
 }
 
-func (s *TransactionService) GetIssues(ctx context.Context, params domain.PaginationParams) (*domain.IssuesResponse, error) {
+// maxHistoryBuckets bounds the buckets of one balance history per currency.
+const maxHistoryBuckets = 5000
 
-	transactions, err := s.repo.GetAll(ctx)
+// GetBalanceHistory returns the balance of every currency per calendar
+// bucket between query.From and query.To.
+func (s *TransactionService) GetBalanceHistory(ctx context.Context, query domain.BalanceHistoryQuery) (*domain.BalanceHistory, error) {
+	if _, err := s.account(ctx, query.AccountID); err != nil {
+		return nil, err
+	}
+	switch query.Interval {
+	case domain.IntervalDay, domain.IntervalWeek, domain.IntervalMonth:
+	default:
+		return nil, fmt.Errorf("%w: unknown interval %q", domain.ErrInvalidRange, query.Interval)
+	}
+	loc := query.Location
+	if loc == nil {
+		loc = s.location
+	}
+
+	all, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counted := make([]domain.Transaction, 0, len(all))
+	for _, tx := range all {
+		if query.AccountID != "" && tx.AccountID != query.AccountID {
+			continue
+		}
+		if _, ok := balanceChange(tx); ok {
+			counted = append(counted, tx)
+		}
+	}
+	sort.SliceStable(counted, func(i, j int) bool {
+		return counted[i].Timestamp.Before(counted[j].Timestamp)
+	})
+
+	history := &domain.BalanceHistory{
+		AccountID:  query.AccountID,
+		Interval:   query.Interval,
+		Timezone:   loc.String(),
+		Currencies: make([]domain.CurrencyHistory, 0),
+	}
+
+	from, to := query.From, query.To
+	for _, bound := range []struct {
+		date string
+		at   *time.Time
+	}{
+		{query.FromDate, &from},
+		{query.ToDate, &to},
+	} {
+		if bound.date == "" {
+			continue
+		}
+		at, err := time.ParseInLocation(time.DateOnly, bound.date, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid date %q", domain.ErrInvalidRange, bound.date)
+		}
+		*bound.at = at
+	}
+	// Without a range the history covers the transactions. A single bound
+	// runs until now or from the first transaction, but never past the
+	// other bound, so a range beyond the data is not an error: after the
+	// last row it is filled with the balance, before the first row it lists
+	// no currencies.
+	switch {
+	case from.IsZero() && to.IsZero():
+		if len(counted) == 0 {
+			return history, nil
+		}
+		from, to = counted[0].Timestamp, counted[len(counted)-1].Timestamp
+	case to.IsZero():
+		to = time.Now()
+		if to.Before(from) {
+			to = from
+		}
+	case from.IsZero():
+		from = to
+		if len(counted) > 0 && counted[0].Timestamp.Before(to) {
+			from = counted[0].Timestamp
+		}
+	}
+	if from.After(to) {
+		return nil, fmt.Errorf("%w: from is after to", domain.ErrInvalidRange)
+	}
+
+	starts := []time.Time{bucketStart(from, query.Interval, loc)}
+	for !nextBucket(starts[len(starts)-1], query.Interval).After(to) {
+		if len(starts) == maxHistoryBuckets {
+			return nil, fmt.Errorf("%w: more than %d buckets", domain.ErrInvalidRange, maxHistoryBuckets)
+		}
+		starts = append(starts, nextBucket(starts[len(starts)-1], query.Interval))
+	}
+	end := nextBucket(starts[len(starts)-1], query.Interval)
+
+	byCurrency := make(map[string][]domain.Transaction)
+	for _, tx := range counted {
+		if tx.Timestamp.Before(end) {
+			byCurrency[tx.Amount.Currency] = append(byCurrency[tx.Amount.Currency], tx)
+		}
+	}
+	for currency, txs := range byCurrency {
+		balance := domain.NewMoney(0, currency)
+		next := 0
+		for ; next < len(txs) && txs[next].Timestamp.Before(starts[0]); next++ {
+			change, _ := balanceChange(txs[next])
+			balance.Minor += change
+		}
+
+		buckets := make([]domain.BalanceBucket, 0, len(starts))
+		for _, start := range starts {
+			bucket := domain.BalanceBucket{
+				Start:   start,
+				End:     nextBucket(start, query.Interval),
+				Opening: balance,
+				Credits: domain.NewMoney(0, currency),
+				Debits:  domain.NewMoney(0, currency),
+			}
+			for ; next < len(txs) && txs[next].Timestamp.Before(bucket.End); next++ {
+				change, _ := balanceChange(txs[next])
+				if change >= 0 {
+					bucket.Credits.Minor += change
+				} else {
+					bucket.Debits.Minor -= change
+				}
+				balance.Minor += change
+			}
+			bucket.Closing = balance
+			buckets = append(buckets, bucket)
+		}
+		history.Currencies = append(history.Currencies, domain.CurrencyHistory{Currency: currency, Buckets: buckets})
+	}
+	sort.Slice(history.Currencies, func(i, j int) bool {
+		return history.Currencies[i].Currency < history.Currencies[j].Currency
+	})
+	return history, nil
+}
+
+// bucketStart returns the start of the calendar bucket in loc holding t.
+func bucketStart(t time.Time, interval domain.Interval, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	switch interval {
+	case domain.IntervalMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	case domain.IntervalWeek:
+		day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+func nextBucket(start time.Time, interval domain.Interval) time.Time {
+	switch interval {
+	case domain.IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	case domain.IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// balances totals the transactions per currency. Flagged duplicates are
+// left out of every total until they are kept.
+func balances(transactions []domain.Transaction) []domain.CurrencyBalance {
+	totals := make(map[string]*domain.CurrencyBalance)
+	for _, tx := range transactions {
+		if tx.Duplicate != nil {
+			continue
+		}
+
+		currency := tx.Amount.Currency
+		total, ok := totals[currency]
+		if !ok {
+			zero := domain.NewMoney(0, currency)
+			total = &domain.CurrencyBalance{
+				Currency:  currency,
+				Balance:   zero,
+				Credits:   zero,
+				Debits:    zero,
+				Pending:   domain.PendingBalance{Credits: zero, Debits: zero, Net: zero},
+				Projected: zero,
+				Failed:    zero,
+				Counts: map[domain.TransactionStatus]int{
+					domain.StatusSuccess: 0,
+					domain.StatusPending: 0,
+					domain.StatusFailed:  0,
+				},
+			}
+			totals[currency] = total
+		}
+		total.Counts[tx.Status]++
+
+		var credits, debits *domain.Money
+		switch tx.Status {
+		case domain.StatusSuccess:
+			credits, debits = &total.Credits, &total.Debits
+		case domain.StatusPending:
+			credits, debits = &total.Pending.Credits, &total.Pending.Debits
+		case domain.StatusFailed:
+			total.Failed.Minor += tx.Amount.Minor
+		}
+		switch {
+		case credits == nil:
+		case tx.Type == domain.TypeCredit:
+			credits.Minor += tx.Amount.Minor
+		case tx.Type == domain.TypeDebit:
+			debits.Minor += tx.Amount.Minor
+		}
+	}
+
+	result := make([]domain.CurrencyBalance, 0, len(totals))
+	for _, total := range totals {
+		total.Balance.Minor = total.Credits.Minor - total.Debits.Minor
+		total.Pending.Net.Minor = total.Pending.Credits.Minor - total.Pending.Debits.Minor
+		total.Projected.Minor = total.Balance.Minor + total.Pending.Net.Minor
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Currency < result[j].Currency
+	})
+	return result
+}
+
+// balanceChange returns the signed amount a transaction adds to its
+// account balance. Only successful transactions count, and flagged
+// duplicates do not count until they are kept.
+func balanceChange(tx domain.Transaction) (int64, bool) {
+	if tx.Status != domain.StatusSuccess || tx.Duplicate != nil {
+		return 0, false
+	}
+	switch tx.Type {
+	case domain.TypeCredit:
+		return tx.Amount.Minor, true
+	case domain.TypeDebit:
+		return -tx.Amount.Minor, true
+	}
+	return 0, false
+}
+
+// setRunningBalances sets the balance of the account and currency right
+// after each transaction, going through them in timestamp order. Rows that
+// do not count towards the balance carry the balance they were booked at.
+func setRunningBalances(transactions []domain.Transaction) {
+	order := make([]int, len(transactions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return transactions[order[i]].Timestamp.Before(transactions[order[j]].Timestamp)
+	})
+
+	type key struct{ account, currency string }
+	running := make(map[key]int64)
+	for _, i := range order {
+		tx := &transactions[i]
+		k := key{tx.AccountID, tx.Amount.Currency}
+		if change, ok := balanceChange(*tx); ok {
+			running[k] += change
+		}
+		balance := domain.NewMoney(running[k], tx.Amount.Currency)
+		tx.RunningBalance = &balance
+	}
+}
+
+// GetIssues lists the transactions matching an issue rule, each with the
+// rules it matched and its issue record, next to the overdraft periods.
+func (s *TransactionService) GetIssues(ctx context.Context, filter domain.IssueFilter, params domain.PaginationParams) (*domain.IssuesResponse, error) {
+	records, err := s.issueRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	states := filter.States
+	if len(states) == 0 {
+		states = []domain.IssueState{domain.IssueOpen, domain.IssueAcknowledged}
+	}
+	wanted := func(code string) bool {
+		return len(filter.Rules) == 0 || slices.Contains(filter.Rules, code)
+	}
+
+	txFilter := domain.TransactionFilter{
+		AccountID:         filter.AccountID,
+		ExcludeDuplicates: true,
+	}
+	issues, metadata, err := s.list(ctx, txFilter, params, func(tx *domain.Transaction) bool {
+		for _, match := range s.issues.Match(*tx) {
+			if wanted(match.Code) {
+				tx.Issues = append(tx.Issues, match)
+			}
+		}
+		if len(tx.Issues) == 0 {
+			return false
+		}
+		record := recordFor(records, issueID(tx.ID), domain.IssueKindTransaction, tx.AccountID)
+		tx.Issue = &record
+		return slices.Contains(states, record.State)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	overdrafts, err := s.GetOverdrafts(ctx, filter.AccountID, domain.Money{})
+	if err != nil {
+		return nil, err
+	}
+	periods := make([]domain.OverdraftPeriod, 0)
+	for _, period := range overdrafts.Periods {
+		record := recordFor(records, period.ID, domain.IssueKindOverdraft, period.AccountID)
+		if wanted(domain.IssueCodeOverdraft) && slices.Contains(states, record.State) {
+			period.Issue = &record
+			periods = append(periods, period)
+		}
+	}
+
+	return &domain.IssuesResponse{
+		Transactions: issues,
+		Overdrafts:   periods,
+		Metadata:     metadata,
+	}, nil
+}
+
+// GetIssue returns the record of a current issue, or of a past one that
+// has been worked on.
+func (s *TransactionService) GetIssue(ctx context.Context, id string) (*domain.IssueRecord, error) {
+	return s.issue(ctx, id)
+}
+
+// UpdateIssue takes an action on an issue and records it in the audit
+// trail.
+func (s *TransactionService) UpdateIssue(ctx context.Context, id string, action domain.IssueAction, update domain.IssueUpdate) (*domain.IssueRecord, error) {
+	if s.issueRepo == nil {
+		return nil, errors.New("issue tracking is not configured")
+	}
+	update.Actor = strings.TrimSpace(update.Actor)
+	update.Comment = strings.TrimSpace(update.Comment)
+	if update.Actor == "" {
+		return nil, errors.New("actor is required")
+	}
+
+	record, err := s.issue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transition is checked against the latest state of the record, so
+	// concurrent actions on one issue all end up in its history.
+	return s.issueRepo.Update(ctx, *record, func(record *domain.IssueRecord) error {
+		from := record.State
+		switch action {
+		case domain.IssueAcknowledge:
+			if record.State != domain.IssueOpen {
+				return fmt.Errorf("%w: cannot acknowledge a %s issue", domain.ErrInvalidTransition, record.State)
+			}
+			record.State = domain.IssueAcknowledged
+		case domain.IssueResolve:
+			if record.State == domain.IssueResolved {
+				return fmt.Errorf("%w: issue is already resolved", domain.ErrInvalidTransition)
+			}
+			record.State = domain.IssueResolved
+		case domain.IssueReopen:
+			if record.State == domain.IssueOpen {
+				return fmt.Errorf("%w: issue is already open", domain.ErrInvalidTransition)
+			}
+			record.State = domain.IssueOpen
+		case domain.IssueAssign:
+			record.Assignee = strings.TrimSpace(update.Assignee)
+		case domain.IssueComment:
+			if update.Comment == "" {
+				return errors.New("comment is required")
+			}
+		default:
+			return fmt.Errorf("unknown issue action %q", action)
+		}
+
+		now := time.Now()
+		event := domain.IssueEvent{Action: action, Actor: update.Actor, At: now, From: from, To: record.State}
+		if action == domain.IssueAssign {
+			event.Assignee = record.Assignee
+		}
+		record.History = append(record.History, event)
+		if update.Comment != "" {
+			record.Comments = append(record.Comments, domain.IssueNote{Author: update.Actor, Text: update.Comment, At: now})
+		}
+		return nil
+	})
+}
+
+// issue returns the stored record of an issue, or a new open one if the ID
+// is a current issue that has none yet.
+func (s *TransactionService) issue(ctx context.Context, id string) (*domain.IssueRecord, error) {
+	if s.issueRepo != nil {
+		record, err := s.issueRepo.Get(ctx, issueID(id))
+		if err == nil {
+			return record, nil
+		}
+		if !errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	if strings.HasPrefix(id, overdraftIDPrefix) {
+		overdrafts, err := s.GetOverdrafts(ctx, "", domain.Money{})
+		if err != nil {
+			return nil, err
+		}
+		for _, period := range overdrafts.Periods {
+			if period.ID == id {
+				record := recordFor(nil, id, domain.IssueKindOverdraft, period.AccountID)
+				return &record, nil
+			}
+		}
+		return nil, domain.ErrNotFound
+	}
+
+	tx, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Duplicate != nil || len(s.issues.Match(*tx)) == 0 {
+		return nil, domain.ErrNotFound
+	}
+	record := recordFor(nil, issueID(id), domain.IssueKindTransaction, tx.AccountID)
+	return &record, nil
+}
+
+// duplicateIDSuffix is added, with the upload ID, to the ID of a flagged or
+// kept copy of a stored transaction.
+const duplicateIDSuffix = "-dup-"
+
+// issueID returns the ID issue records of a transaction are kept under. A
+// copy of a transaction shares the issue of the original, so resolving it
+// once is enough however often the row is uploaded.
+func issueID(txID string) string {
+	id, _, _ := strings.Cut(txID, duplicateIDSuffix)
+	return id
+}
+
+// issueRecords returns the stored issue records by ID.
+func (s *TransactionService) issueRecords(ctx context.Context) (map[string]domain.IssueRecord, error) {
+	records := make(map[string]domain.IssueRecord)
+	if s.issueRepo == nil {
+		return records, nil
+	}
+	list, err := s.issueRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range list {
+		records[record.ID] = record
+	}
+	return records, nil
+}
+
+// recordFor returns the stored record of an issue, or a new open one.
+func recordFor(records map[string]domain.IssueRecord, id string, kind domain.IssueKind, accountID string) domain.IssueRecord {
+	if record, ok := records[id]; ok {
+		return record
+	}
+	return domain.IssueRecord{
+		ID:        id,
+		Kind:      kind,
+		AccountID: accountID,
+		State:     domain.IssueOpen,
+		Comments:  make([]domain.IssueNote, 0),
+		History:   make([]domain.IssueEvent, 0),
+	}
+}
+
+func (s *TransactionService) GetOverdrafts(ctx context.Context, accountID string, threshold domain.Money) (*domain.OverdraftReport, error) {
+	if _, err := s.account(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	all, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]domain.Transaction, 0, len(all))
+	for _, tx := range all {
+		if accountID == "" || tx.AccountID == accountID {
+			transactions = append(transactions, tx)
+		}
+	}
+
+	return &domain.OverdraftReport{
+		AccountID: accountID,
+		Threshold: threshold,
+		Periods:   overdrafts(transactions, threshold, time.Now()),
+	}, nil
+}
+
+// overdraftIDPrefix starts the ID of an overdraft period, followed by the
+// ID of the transaction that started it.
+const overdraftIDPrefix = "overdraft-"
+
+// overdrafts follows the balance of every account and currency in
+// timestamp order and returns the periods it spent below threshold, oldest
+// first. Periods still open are measured until now.
+func overdrafts(transactions []domain.Transaction, threshold domain.Money, now time.Time) []domain.OverdraftPeriod {
+	counted := make([]domain.Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if _, ok := balanceChange(tx); ok {
+			counted = append(counted, tx)
+		}
+	}
+	sort.SliceStable(counted, func(i, j int) bool {
+		return counted[i].Timestamp.Before(counted[j].Timestamp)
+	})
+
+	type key struct{ account, currency string }
+	running := make(map[key]int64)
+	open := make(map[key]*domain.OverdraftPeriod)
+	periods := make([]domain.OverdraftPeriod, 0)
+	for _, tx := range counted {
+		k := key{tx.AccountID, tx.Amount.Currency}
+		change, _ := balanceChange(tx)
+		running[k] += change
+		balance := domain.NewMoney(running[k], tx.Amount.Currency)
+		below := balance.Cmp(threshold) < 0
+
+		period, ok := open[k]
+		switch {
+		case below && !ok:
+			open[k] = &domain.OverdraftPeriod{
+				ID:        overdraftIDPrefix + tx.ID,
+				AccountID: tx.AccountID,
+				Currency:  tx.Amount.Currency,
+				Start:     tx.Timestamp,
+				Lowest:    balance,
+				LowestAt:  tx.Timestamp,
+				StartedBy: tx.ID,
+			}
+		case below && balance.Minor < period.Lowest.Minor:
+			period.Lowest = balance
+			period.LowestAt = tx.Timestamp
+		case !below && ok:
+			end := tx.Timestamp
+			period.End = &end
+			period.EndedBy = tx.ID
+			period.Duration = int64(end.Sub(period.Start) / time.Second)
+			periods = append(periods, *period)
+			delete(open, k)
+		}
+	}
+	for _, period := range open {
+		period.Duration = int64(now.Sub(period.Start) / time.Second)
+		periods = append(periods, *period)
+	}
+
+	sort.SliceStable(periods, func(i, j int) bool {
+		if !periods[i].Start.Equal(periods[j].Start) {
+			return periods[i].Start.Before(periods[j].Start)
+		}
+		return periods[i].ID < periods[j].ID
+	})
+	return periods
+}
+
+func (s *TransactionService) ListTransactions(ctx context.Context, filter domain.TransactionFilter, params domain.PaginationParams) (*domain.TransactionListResponse, error) {
+	transactions, metadata, err := s.list(ctx, filter, params, nil)
 	if err != nil {
 		return nil, err
 	}
+	return &domain.TransactionListResponse{
+		Transactions: transactions,
+		Metadata:     metadata,
+	}, nil
+}
+
+// list returns one sorted page of the stored transactions matching filter
+// and, when it is set, keep. keep may annotate the transaction it is given.
+func (s *TransactionService) list(ctx context.Context, filter domain.TransactionFilter, params domain.PaginationParams, keep func(*domain.Transaction) bool) ([]domain.Transaction, domain.PaginationMetadata, error) {
+	if _, err := s.account(ctx, filter.AccountID); err != nil {
+		return nil, domain.PaginationMetadata{}, err
+	}
+
+	transactions, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, domain.PaginationMetadata{}, err
+	}
+	// Running balances cover the whole account, not just the matches.
+	setRunningBalances(transactions)
 
-	issues := make([]domain.Transaction, 0)
+	matches := make([]domain.Transaction, 0)
 	for _, tx := range transactions {
-		if tx.Status == domain.StatusFailed || tx.Status == domain.StatusPending {
-			issues = append(issues, tx)
+		if filter.Matches(tx) && (keep == nil || keep(&tx)) {
+			matches = append(matches, tx)
 		}
 	}
 
-	sort.Slice(issues, func(i, j int) bool {
+	sort.Slice(matches, func(i, j int) bool {
 		switch params.SortBy {
 		case "amount":
 			if params.SortDir == "asc" {
-				return issues[i].Amount < issues[j].Amount
+				return matches[i].Amount.Cmp(matches[j].Amount) < 0
 			}
-			return issues[i].Amount > issues[j].Amount
+			return matches[i].Amount.Cmp(matches[j].Amount) > 0
 		case "name":
 			if params.SortDir == "asc" {
-				return issues[i].Name < issues[j].Name
+				return matches[i].Name < matches[j].Name
 			}
-			return issues[i].Name > issues[j].Name
+			return matches[i].Name > matches[j].Name
 		default:
 			if params.SortDir == "asc" {
-				return issues[i].Timestamp.Before(issues[j].Timestamp)
+				return matches[i].Timestamp.Before(matches[j].Timestamp)
 			}
-			return issues[i].Timestamp.After(issues[j].Timestamp)
+			return matches[i].Timestamp.After(matches[j].Timestamp)
 		}
 	})
 
-	totalItems := len(issues)
+	totalItems := len(matches)
 	totalPages := int(math.Ceil(float64(totalItems) / float64(params.Limit)))
 
 	startIndex := (params.Page - 1) * params.Limit
@@ -104,8 +1241,6 @@ func (s *TransactionService) GetIssues(ctx context.Context, params domain.Pagina
 		endIndex = totalItems
 	}
 
-	pageData := issues[startIndex:endIndex]
-
 	metadata := domain.PaginationMetadata{
 		CurrentPage: params.Page,
 		PageSize:    params.Limit,
@@ -113,10 +1248,5 @@ func (s *TransactionService) GetIssues(ctx context.Context, params domain.Pagina
 		TotalPages:  totalPages,
 	}
 
-	response := &domain.IssuesResponse{
-		Transactions: pageData,
-		Metadata:     metadata,
-	}
-
-	return response, nil
+	return matches[startIndex:endIndex], metadata, nil
 }
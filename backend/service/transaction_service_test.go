@@ -2,13 +2,25 @@
 package service
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/novanm/bank-viewer/backend/domain"
+	"github.com/novanm/bank-viewer/backend/pkg/camtparser"
+	"github.com/novanm/bank-viewer/backend/pkg/csvparser"
+	"github.com/novanm/bank-viewer/backend/pkg/issuerule"
+	"github.com/novanm/bank-viewer/backend/pkg/mt940parser"
+	"github.com/novanm/bank-viewer/backend/pkg/ofxparser"
+	"github.com/novanm/bank-viewer/backend/pkg/registry"
+	"github.com/novanm/bank-viewer/backend/pkg/validation"
+	"github.com/novanm/bank-viewer/backend/repository/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -17,9 +29,10 @@ type MockTransactionRepository struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRepository) Store(ctx context.Context, txs []domain.Transaction) error {
-	args := m.Called(ctx, txs)
-	return args.Error(0)
+func (m *MockTransactionRepository) Store(ctx context.Context, upload domain.Upload, txs []domain.Transaction) (*domain.Upload, error) {
+	args := m.Called(ctx, upload, txs)
+	stored, _ := args.Get(0).(*domain.Upload)
+	return stored, args.Error(1)
 }
 
 func (m *MockTransactionRepository) GetAll(ctx context.Context) ([]domain.Transaction, error) {
@@ -27,18 +40,106 @@ func (m *MockTransactionRepository) GetAll(ctx context.Context) ([]domain.Transa
 	return args.Get(0).([]domain.Transaction), args.Error(1)
 }
 
+func (m *MockTransactionRepository) GetByID(ctx context.Context, id string) (*domain.Transaction, error) {
+	args := m.Called(ctx, id)
+	tx, _ := args.Get(0).(*domain.Transaction)
+	return tx, args.Error(1)
+}
+
+func (m *MockTransactionRepository) Begin(ctx context.Context, upload domain.Upload) (domain.TransactionWriter, error) {
+	args := m.Called(ctx, upload)
+	return args.Get(0).(domain.TransactionWriter), args.Error(1)
+}
+
+func (m *MockTransactionRepository) ListUploads(ctx context.Context) ([]domain.Upload, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Upload), args.Error(1)
+}
+
+func (m *MockTransactionRepository) DeleteUpload(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) UpdateTransaction(ctx context.Context, tx domain.Transaction) error {
+	args := m.Called(ctx, tx)
+	return args.Error(0)
+}
+
+func (m *MockTransactionRepository) DeleteTransaction(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+type MockTransactionWriter struct {
+	mock.Mock
+}
+
+func (m *MockTransactionWriter) Write(ctx context.Context, txs []domain.Transaction) error {
+	// The service reuses its batch buffer, so record a copy.
+	args := m.Called(ctx, append([]domain.Transaction(nil), txs...))
+	return args.Error(0)
+}
+
+func (m *MockTransactionWriter) Update(ctx context.Context, tx domain.Transaction) error {
+	args := m.Called(ctx, tx)
+	return args.Error(0)
+}
+
+func (m *MockTransactionWriter) Commit(ctx context.Context) (*domain.Upload, error) {
+	args := m.Called(ctx)
+	upload, _ := args.Get(0).(*domain.Upload)
+	return upload, args.Error(1)
+}
+
+func (m *MockTransactionWriter) Rollback(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+// expectImport makes the repository hand out a writer that expects to be
+// committed. Tests that expect a failure assert Commit was not called.
+func expectImport(repo *MockTransactionRepository) *MockTransactionWriter {
+	writer := new(MockTransactionWriter)
+	expectNoStoredTransactions(repo)
+	repo.On("Begin", mock.Anything, mock.Anything).Return(writer, nil)
+	writer.On("Commit", mock.Anything).Return(&domain.Upload{Version: 1}, nil)
+	writer.On("Rollback", mock.Anything).Return(nil).Maybe()
+	return writer
+}
+
+// expectNoStoredTransactions lets uploads look for duplicates in an empty
+// repository.
+func expectNoStoredTransactions(repo *MockTransactionRepository) {
+	repo.On("GetAll", mock.Anything).Return([]domain.Transaction{}, nil).Maybe()
+}
+
+func idr(major int64) domain.Money {
+	return domain.NewMoney(major*100, "IDR")
+}
+
 var t1 = time.Now()
 var t2 = t1.Add(1 * time.Hour)
 var t3 = t1.Add(2 * time.Hour)
 
 var mockData = []domain.Transaction{
 
-	{Timestamp: t1, Name: "COMPANY A", Type: domain.TypeCredit, Amount: 1000, Status: domain.StatusSuccess},
-	{Timestamp: t2, Name: "RESTAURANT", Type: domain.TypeDebit, Amount: 100, Status: domain.StatusSuccess},
+	{Timestamp: t1, Name: "COMPANY A", Type: domain.TypeCredit, Amount: idr(1000), Status: domain.StatusSuccess},
+	{Timestamp: t2, Name: "RESTAURANT", Type: domain.TypeDebit, Amount: idr(100), Status: domain.StatusSuccess},
 
-	{Timestamp: t2, Name: "E-COMMERCE", Type: domain.TypeDebit, Amount: 50, Status: domain.StatusFailed},
+	{Timestamp: t2, Name: "E-COMMERCE", Type: domain.TypeDebit, Amount: idr(50), Status: domain.StatusFailed},
 
-	{Timestamp: t3, Name: "TRANSFER", Type: domain.TypeCredit, Amount: 200, Status: domain.StatusPending},
+	{Timestamp: t3, Name: "TRANSFER", Type: domain.TypeCredit, Amount: idr(200), Status: domain.StatusPending},
+}
+
+func newParsers() *registry.Registry {
+	parsers := registry.New()
+	parsers.Register(ofxparser.New())
+	parsers.Register(camtparser.New())
+	parsers.Register(mt940parser.New())
+	parsers.Register(csvparser.New())
+	parsers.SetFallback(csvparser.New())
+	return parsers
 }
 
 func TestGetBalance_Success(t *testing.T) {
@@ -47,15 +148,267 @@ func TestGetBalance_Success(t *testing.T) {
 
 	s := NewTransactionService(mockRepo)
 
-	balance, err := s.GetBalance(context.Background())
+	balance, err := s.GetBalance(context.Background(), "", time.Time{})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, balance)
 
-	assert.Equal(t, int64(900), balance.TotalBalance)
+	assert.Equal(t, 1, len(balance.Balances))
+	assert.Equal(t, "IDR", balance.Balances[0].Currency)
+	assert.Equal(t, idr(900), balance.Balances[0].Balance)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetBalance_PerCurrency(t *testing.T) {
+	mixed := []domain.Transaction{
+		{Timestamp: t1, Name: "COMPANY A", Type: domain.TypeCredit, Amount: idr(1000), Status: domain.StatusSuccess},
+		{Timestamp: t2, Name: "US CLIENT", Type: domain.TypeCredit, Amount: domain.NewMoney(12550, "USD"), Status: domain.StatusSuccess},
+		{Timestamp: t3, Name: "US SHOP", Type: domain.TypeDebit, Amount: domain.NewMoney(2000, "USD"), Status: domain.StatusSuccess},
+	}
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(mixed, nil)
+
+	s := NewTransactionService(mockRepo)
+
+	balance, err := s.GetBalance(context.Background(), "", time.Time{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(balance.Balances))
+	assert.Equal(t, "IDR", balance.Balances[0].Currency)
+	assert.Equal(t, idr(1000), balance.Balances[0].Balance)
+	assert.Equal(t, "USD", balance.Balances[1].Currency)
+	assert.Equal(t, "105.50", balance.Balances[1].Balance.String())
 	mockRepo.AssertExpectations(t)
 }
 
+func TestGetBalance_Breakdown(t *testing.T) {
+	data := append([]domain.Transaction{
+		{Timestamp: t3, Name: "REFUND", Type: domain.TypeDebit, Amount: idr(30), Status: domain.StatusPending},
+		{Timestamp: t3, Name: "TRANSFER", Type: domain.TypeCredit, Amount: idr(70), Status: domain.StatusFailed},
+		{Timestamp: t3, Name: "COPY", Type: domain.TypeCredit, Amount: idr(1000), Status: domain.StatusSuccess, Duplicate: &domain.DuplicateMatch{Of: "a", Rule: "fuzzy"}},
+	}, mockData...)
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(data, nil)
+
+	s := NewTransactionService(mockRepo)
+
+	balance, err := s.GetBalance(context.Background(), "", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.CurrencyBalance{{
+		Currency:  "IDR",
+		Balance:   idr(900),
+		Credits:   idr(1000),
+		Debits:    idr(100),
+		Pending:   domain.PendingBalance{Credits: idr(200), Debits: idr(30), Net: idr(170)},
+		Projected: idr(1070),
+		Failed:    idr(120),
+		Counts:    map[domain.TransactionStatus]int{domain.StatusSuccess: 2, domain.StatusPending: 2, domain.StatusFailed: 2},
+	}}, balance.Balances)
+}
+
+func TestGetBalance_At(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(mockData, nil)
+
+	s := NewTransactionService(mockRepo)
+
+	balance, err := s.GetBalance(context.Background(), "", t1)
+	assert.NoError(t, err)
+	assert.Equal(t, idr(1000), balance.Balances[0].Balance, "the cut-off is inclusive")
+	assert.Equal(t, t1, *balance.At)
+
+	balance, err = s.GetBalance(context.Background(), "", t1.Add(-time.Second))
+	assert.NoError(t, err)
+	assert.Empty(t, balance.Balances)
+
+	balance, err = s.GetBalance(context.Background(), "", time.Time{})
+	assert.NoError(t, err)
+	assert.Nil(t, balance.At)
+}
+
+func TestListTransactions_RunningBalance(t *testing.T) {
+	data := []domain.Transaction{
+		{ID: "late", AccountID: "checking", Timestamp: t3, Type: domain.TypeDebit, Amount: idr(100), Status: domain.StatusSuccess},
+		{ID: "first", AccountID: "checking", Timestamp: t1, Type: domain.TypeCredit, Amount: idr(1000), Status: domain.StatusSuccess},
+		{ID: "failed", AccountID: "checking", Timestamp: t2, Type: domain.TypeDebit, Amount: idr(50), Status: domain.StatusFailed},
+		{ID: "savings", AccountID: "savings", Timestamp: t2, Type: domain.TypeCredit, Amount: idr(500), Status: domain.StatusSuccess},
+		{ID: "usd", AccountID: "checking", Timestamp: t2, Type: domain.TypeCredit, Amount: domain.NewMoney(1000, "USD"), Status: domain.StatusSuccess},
+	}
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(data, nil)
+
+	s := NewTransactionService(mockRepo)
+
+	result, err := s.ListTransactions(context.Background(), domain.TransactionFilter{AccountID: "checking", Currency: "IDR"}, domain.PaginationParams{Page: 1, Limit: 10, SortDir: "asc"})
+	assert.NoError(t, err)
+	running := make(map[string]domain.Money)
+	for _, tx := range result.Transactions {
+		running[tx.ID] = *tx.RunningBalance
+	}
+	assert.Equal(t, map[string]domain.Money{"first": idr(1000), "failed": idr(1000), "late": idr(900)}, running)
+
+	issues, err := s.GetIssues(context.Background(), domain.IssueFilter{}, domain.PaginationParams{Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, idr(1000), *issues.Transactions[0].RunningBalance)
+}
+
+func TestGetBalanceHistory(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, 6, day, hour, 0, 0, 0, jakarta)
+	}
+	data := []domain.Transaction{
+		{Timestamp: at(3, 9), Type: domain.TypeCredit, Amount: idr(1000), Status: domain.StatusSuccess},
+		{Timestamp: at(3, 20), Type: domain.TypeDebit, Amount: idr(100), Status: domain.StatusSuccess},
+		{Timestamp: at(4, 1), Type: domain.TypeDebit, Amount: idr(50), Status: domain.StatusPending},
+		{Timestamp: at(6, 6), Type: domain.TypeDebit, Amount: idr(200), Status: domain.StatusSuccess},
+		{Timestamp: at(10, 12), Type: domain.TypeCredit, Amount: domain.NewMoney(500, "USD"), Status: domain.StatusSuccess},
+	}
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(data, nil)
+
+	s := NewTransactionService(mockRepo, WithLocation(jakarta))
+
+	history, err := s.GetBalanceHistory(context.Background(), domain.BalanceHistoryQuery{Interval: domain.IntervalDay, FromDate: "2024-06-04", ToDate: "2024-06-06"})
+	assert.NoError(t, err)
+	assert.Equal(t, "WIB", history.Timezone)
+	assert.Equal(t, 1, len(history.Currencies), "USD has no rows up to the end")
+	buckets := history.Currencies[0].Buckets
+	assert.Equal(t, 3, len(buckets))
+	assert.Equal(t, domain.BalanceBucket{Start: at(4, 0), End: at(5, 0), Opening: idr(900), Closing: idr(900), Credits: idr(0), Debits: idr(0)}, buckets[0])
+	assert.Equal(t, idr(900), buckets[1].Closing, "empty buckets are filled")
+	assert.Equal(t, domain.BalanceBucket{Start: at(6, 0), End: at(7, 0), Opening: idr(900), Closing: idr(700), Credits: idr(0), Debits: idr(200)}, buckets[2])
+
+	// 2024-06-03 is a Monday; the range defaults to the first and last row.
+	history, err = s.GetBalanceHistory(context.Background(), domain.BalanceHistoryQuery{Interval: domain.IntervalWeek})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"IDR", "USD"}, []string{history.Currencies[0].Currency, history.Currencies[1].Currency})
+	buckets = history.Currencies[0].Buckets
+	assert.Equal(t, 2, len(buckets))
+	assert.Equal(t, domain.BalanceBucket{Start: at(3, 0), End: at(10, 0), Opening: idr(0), Closing: idr(700), Credits: idr(1000), Debits: idr(300)}, buckets[0])
+	assert.Equal(t, "5.00", history.Currencies[1].Buckets[1].Closing.String())
+
+	history, err = s.GetBalanceHistory(context.Background(), domain.BalanceHistoryQuery{Interval: domain.IntervalMonth, Location: time.UTC})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), history.Currencies[0].Buckets[0].Start)
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), history.Currencies[0].Buckets[0].End)
+
+	// A from after the last row runs until now with the balance carried
+	// through every bucket.
+	history, err = s.GetBalanceHistory(context.Background(), domain.BalanceHistoryQuery{Interval: domain.IntervalMonth, FromDate: "2024-08-01"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(history.Currencies))
+	buckets = history.Currencies[0].Buckets
+	assert.Equal(t, time.Date(2024, 8, 1, 0, 0, 0, 0, jakarta), buckets[0].Start)
+	assert.True(t, buckets[len(buckets)-1].End.After(time.Now()))
+	for _, bucket := range buckets {
+		assert.Equal(t, domain.BalanceBucket{Start: bucket.Start, End: bucket.End, Opening: idr(700), Closing: idr(700), Credits: idr(0), Debits: idr(0)}, bucket)
+	}
+
+	// A to before the first row is valid, but like USD above no currency
+	// has a row up to its end, so none is listed.
+	history, err = s.GetBalanceHistory(context.Background(), domain.BalanceHistoryQuery{Interval: domain.IntervalDay, ToDate: "2024-05-01"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(history.Currencies))
+
+	history, err = s.GetBalanceHistory(context.Background(), domain.BalanceHistoryQuery{Interval: domain.IntervalWeek, ToDate: "2024-06-04"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(history.Currencies[0].Buckets))
+	assert.Equal(t, at(3, 0), history.Currencies[0].Buckets[0].Start, "to alone runs from the first row")
+
+	_, err = s.GetBalanceHistory(context.Background(), domain.BalanceHistoryQuery{Interval: domain.IntervalDay, FromDate: "2024-06-06", ToDate: "2024-06-04"})
+	assert.ErrorIs(t, err, domain.ErrInvalidRange)
+
+	_, err = s.GetBalanceHistory(context.Background(), domain.BalanceHistoryQuery{Interval: domain.IntervalDay, FromDate: "2000-01-01"})
+	assert.ErrorIs(t, err, domain.ErrInvalidRange, "too many buckets")
+}
+
+func TestGetOverdrafts(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 6, d, 0, 0, 0, 0, time.UTC)
+	}
+	data := []domain.Transaction{
+		{ID: "salary", AccountID: "checking", Timestamp: day(1), Type: domain.TypeCredit, Amount: idr(100), Status: domain.StatusSuccess},
+		{ID: "rent", AccountID: "checking", Timestamp: day(2), Type: domain.TypeDebit, Amount: idr(150), Status: domain.StatusSuccess},
+		{ID: "failed", AccountID: "checking", Timestamp: day(3), Type: domain.TypeDebit, Amount: idr(500), Status: domain.StatusFailed},
+		{ID: "card", AccountID: "checking", Timestamp: day(3), Type: domain.TypeDebit, Amount: idr(30), Status: domain.StatusSuccess},
+		{ID: "refund", AccountID: "checking", Timestamp: day(5), Type: domain.TypeCredit, Amount: idr(80), Status: domain.StatusSuccess},
+		{ID: "fee", AccountID: "checking", Timestamp: day(8), Type: domain.TypeDebit, Amount: idr(10), Status: domain.StatusSuccess},
+		{ID: "savings", AccountID: "savings", Timestamp: day(1), Type: domain.TypeCredit, Amount: idr(10), Status: domain.StatusSuccess},
+	}
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(data, nil)
+
+	s := NewTransactionService(mockRepo)
+
+	report, err := s.GetOverdrafts(context.Background(), "", domain.Money{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(report.Periods))
+	end := day(5)
+	assert.Equal(t, domain.OverdraftPeriod{
+		ID: "overdraft-rent", AccountID: "checking", Currency: "IDR",
+		Start: day(2), End: &end, Lowest: idr(-80), LowestAt: day(3),
+		Duration: 3 * 24 * 60 * 60, StartedBy: "rent", EndedBy: "refund",
+	}, report.Periods[0])
+	assert.Equal(t, "overdraft-fee", report.Periods[1].ID)
+	assert.Nil(t, report.Periods[1].End, "the last period is still ongoing")
+	assert.Greater(t, report.Periods[1].Duration, int64(0))
+
+	// Below 20.000: the first period starts with the rent and the refund to
+	// 0 does not end it.
+	report, err = s.GetOverdrafts(context.Background(), "checking", domain.Money{Minor: 20000, Exponent: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(report.Periods))
+	assert.Equal(t, "rent", report.Periods[0].StartedBy)
+
+	issues, err := s.GetIssues(context.Background(), domain.IssueFilter{AccountID: "checking"}, domain.PaginationParams{Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(issues.Transactions))
+	assert.Equal(t, 2, len(issues.Overdrafts))
+
+	issues, err = s.GetIssues(context.Background(), domain.IssueFilter{AccountID: "savings"}, domain.PaginationParams{Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, issues.Overdrafts)
+}
+
+func TestGetBalance_PerAccount(t *testing.T) {
+	data := []domain.Transaction{
+		{AccountID: "checking", Type: domain.TypeCredit, Amount: idr(1000), Status: domain.StatusSuccess},
+		{AccountID: "checking", Type: domain.TypeDebit, Amount: idr(100), Status: domain.StatusSuccess},
+		{AccountID: "savings", Type: domain.TypeCredit, Amount: idr(500), Status: domain.StatusSuccess},
+		{AccountID: "savings", Type: domain.TypeCredit, Amount: idr(50), Status: domain.StatusPending},
+	}
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(data, nil)
+	accounts := new(MockAccountRepository)
+	accounts.On("Get", mock.Anything, "savings").Return(&domain.Account{ID: "savings"}, nil)
+	accounts.On("Get", mock.Anything, "missing").Return(nil, domain.ErrNotFound)
+
+	s := NewTransactionService(mockRepo, WithAccounts(accounts))
+
+	consolidated, err := s.GetBalance(context.Background(), "", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, idr(1400), consolidated.Balances[0].Balance)
+	assert.Equal(t, 2, len(consolidated.Accounts))
+	assert.Equal(t, "checking", consolidated.Accounts[0].AccountID)
+	assert.Equal(t, idr(900), consolidated.Accounts[0].Balances[0].Balance)
+	assert.Equal(t, "savings", consolidated.Accounts[1].AccountID)
+	assert.Equal(t, idr(500), consolidated.Accounts[1].Balances[0].Balance)
+
+	savings, err := s.GetBalance(context.Background(), "savings", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "savings", savings.AccountID)
+	assert.Equal(t, idr(500), savings.Balances[0].Balance)
+	assert.Empty(t, savings.Accounts)
+
+	_, err = s.GetBalance(context.Background(), "missing", time.Time{})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	issues, err := s.GetIssues(context.Background(), domain.IssueFilter{AccountID: "savings"}, domain.PaginationParams{Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, issues.Metadata.TotalItems)
+}
+
 func TestGetIssues_PaginationAndSorting(t *testing.T) {
 	mockRepo := new(MockTransactionRepository)
 	mockRepo.On("GetAll", mock.Anything).Return(mockData, nil)
@@ -68,7 +421,7 @@ func TestGetIssues_PaginationAndSorting(t *testing.T) {
 		SortDir: "asc",
 	}
 
-	issues, err := s.GetIssues(context.Background(), params)
+	issues, err := s.GetIssues(context.Background(), domain.IssueFilter{}, params)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, issues)
@@ -76,8 +429,8 @@ func TestGetIssues_PaginationAndSorting(t *testing.T) {
 	assert.Equal(t, 2, len(issues.Transactions))
 	assert.Equal(t, 2, issues.Metadata.TotalItems)
 
-	assert.Equal(t, int64(50), issues.Transactions[0].Amount)
-	assert.Equal(t, int64(200), issues.Transactions[1].Amount)
+	assert.Equal(t, idr(50), issues.Transactions[0].Amount)
+	assert.Equal(t, idr(200), issues.Transactions[1].Amount)
 
 	params2 := domain.PaginationParams{
 		Page:    2,
@@ -86,33 +439,241 @@ func TestGetIssues_PaginationAndSorting(t *testing.T) {
 		SortDir: "asc",
 	}
 
-	issues2, err2 := s.GetIssues(context.Background(), params2)
+	issues2, err2 := s.GetIssues(context.Background(), domain.IssueFilter{}, params2)
 
 	assert.NoError(t, err2)
 	assert.NotNil(t, issues2)
-	assert.Equal(t, 2, issues2.Metadata.TotalItems)             // Total tetap 2
-	assert.Equal(t, 2, issues2.Metadata.TotalPages)             // Sekarang ada 2 halaman
-	assert.Equal(t, 2, issues2.Metadata.CurrentPage)            // Kita di halaman 2
-	assert.Equal(t, 1, len(issues2.Transactions))               // Hanya 1 item
-	assert.Equal(t, int64(200), issues2.Transactions[0].Amount) // Item kedua
+	assert.Equal(t, 2, issues2.Metadata.TotalItems)           // Total tetap 2
+	assert.Equal(t, 2, issues2.Metadata.TotalPages)           // Sekarang ada 2 halaman
+	assert.Equal(t, 2, issues2.Metadata.CurrentPage)          // Kita di halaman 2
+	assert.Equal(t, 1, len(issues2.Transactions))             // Hanya 1 item
+	assert.Equal(t, idr(200), issues2.Transactions[0].Amount) // Item kedua
 
 	mockRepo.AssertExpectations(t)
 }
 
+func TestGetIssues_Rules(t *testing.T) {
+	rules, err := issuerule.New(issuerule.Config{Rules: []issuerule.RuleConfig{
+		{Code: "FAILED", Severity: domain.SeverityHigh, Condition: issuerule.ConditionStatus, Statuses: []domain.TransactionStatus{domain.StatusFailed}},
+		{Code: "LARGE_DEBIT", Severity: domain.SeverityCritical, Condition: issuerule.ConditionDebitAbove, Max: "75"},
+	}})
+	assert.NoError(t, err)
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(mockData, nil)
+
+	s := NewTransactionService(mockRepo, WithIssueRules(rules))
+
+	params := domain.PaginationParams{Page: 1, Limit: 10, SortBy: "amount", SortDir: "asc"}
+	issues, err := s.GetIssues(context.Background(), domain.IssueFilter{}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(issues.Transactions), "pending rows are not an issue under these rules")
+	assert.Equal(t, "E-COMMERCE", issues.Transactions[0].Name)
+	assert.Equal(t, []domain.IssueMatch{{Code: "FAILED", Severity: domain.SeverityHigh}}, issues.Transactions[0].Issues)
+	assert.Equal(t, "RESTAURANT", issues.Transactions[1].Name)
+	assert.Equal(t, []domain.IssueMatch{{Code: "LARGE_DEBIT", Severity: domain.SeverityCritical}}, issues.Transactions[1].Issues)
+
+	issues, err = s.GetIssues(context.Background(), domain.IssueFilter{Rules: []string{"LARGE_DEBIT"}}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, issues.Metadata.TotalItems)
+	assert.Equal(t, "RESTAURANT", issues.Transactions[0].Name)
+}
+
+type MockIssueRepository struct {
+	mock.Mock
+}
+
+func (m *MockIssueRepository) Get(ctx context.Context, id string) (*domain.IssueRecord, error) {
+	args := m.Called(ctx, id)
+	record, _ := args.Get(0).(*domain.IssueRecord)
+	return record, args.Error(1)
+}
+
+func (m *MockIssueRepository) List(ctx context.Context) ([]domain.IssueRecord, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.IssueRecord), args.Error(1)
+}
+
+// Update applies fn to the record it is given and reports the result.
+func (m *MockIssueRepository) Update(ctx context.Context, record domain.IssueRecord, fn func(*domain.IssueRecord) error) (*domain.IssueRecord, error) {
+	if err := fn(&record); err != nil {
+		return nil, err
+	}
+	args := m.Called(ctx, record)
+	return &record, args.Error(0)
+}
+
+func TestIssueLifecycle(t *testing.T) {
+	data := []domain.Transaction{
+		{ID: "ok", AccountID: "default", Timestamp: t1, Type: domain.TypeCredit, Amount: idr(1000), Status: domain.StatusSuccess},
+		{ID: "failed", AccountID: "default", Timestamp: t2, Type: domain.TypeDebit, Amount: idr(50), Status: domain.StatusFailed},
+		{ID: "pending", AccountID: "default", Timestamp: t3, Type: domain.TypeCredit, Amount: idr(200), Status: domain.StatusPending},
+	}
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(data, nil)
+	mockRepo.On("GetByID", mock.Anything, "ok").Return(&data[0], nil)
+	mockRepo.On("GetByID", mock.Anything, "failed").Return(&data[1], nil)
+	issueRepo := new(MockIssueRepository)
+	issueRepo.On("Get", mock.Anything, mock.Anything).Return(nil, domain.ErrNotFound).Once()
+
+	s := NewTransactionService(mockRepo, WithIssueTracking(issueRepo))
+	ctx := context.Background()
+	params := domain.PaginationParams{Page: 1, Limit: 10, SortDir: "asc"}
+
+	var saved domain.IssueRecord
+	issueRepo.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(domain.IssueRecord)
+	}).Return(nil).Once()
+
+	record, err := s.UpdateIssue(ctx, "failed", domain.IssueAcknowledge, domain.IssueUpdate{Actor: "ops", Comment: "Asked the bank"})
+	assert.NoError(t, err)
+	assert.Equal(t, saved, *record)
+	assert.Equal(t, domain.IssueAcknowledged, record.State)
+	assert.Equal(t, domain.IssueKindTransaction, record.Kind)
+	assert.Equal(t, "default", record.AccountID)
+	assert.Equal(t, 1, len(record.History))
+	assert.Equal(t, domain.IssueEvent{Action: domain.IssueAcknowledge, Actor: "ops", At: record.History[0].At, From: domain.IssueOpen, To: domain.IssueAcknowledged}, record.History[0])
+	assert.Equal(t, "Asked the bank", record.Comments[0].Text)
+
+	issueRepo.On("Get", mock.Anything, "failed").Return(&saved, nil)
+	_, err = s.UpdateIssue(ctx, "failed", domain.IssueAcknowledge, domain.IssueUpdate{Actor: "ops"})
+	assert.ErrorIs(t, err, domain.ErrInvalidTransition)
+	_, err = s.UpdateIssue(ctx, "failed", domain.IssueComment, domain.IssueUpdate{Actor: "ops"})
+	assert.EqualError(t, err, "comment is required")
+	_, err = s.UpdateIssue(ctx, "failed", domain.IssueResolve, domain.IssueUpdate{})
+	assert.EqualError(t, err, "actor is required")
+
+	issueRepo.On("Get", mock.Anything, "ok").Return(nil, domain.ErrNotFound)
+	_, err = s.GetIssue(ctx, "ok")
+	assert.ErrorIs(t, err, domain.ErrNotFound, "a successful row is no issue")
+
+	resolved := saved
+	resolved.State = domain.IssueResolved
+	issueRepo.On("List", mock.Anything).Return([]domain.IssueRecord{resolved}, nil)
+
+	issues, err := s.GetIssues(ctx, domain.IssueFilter{}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(issues.Transactions), "resolved issues stay hidden")
+	assert.Equal(t, "pending", issues.Transactions[0].ID)
+	assert.Equal(t, domain.IssueOpen, issues.Transactions[0].Issue.State)
+
+	issues, err = s.GetIssues(ctx, domain.IssueFilter{States: []domain.IssueState{domain.IssueResolved}}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(issues.Transactions))
+	assert.Equal(t, "failed", issues.Transactions[0].ID)
+	assert.Equal(t, "Asked the bank", issues.Transactions[0].Issue.Comments[0].Text)
+}
+
+func TestIssueLifecycle_ResolvedStaysHiddenAfterReupload(t *testing.T) {
+	csvData := `timestamp,name,type,amount,status
+2024-06-01T10:00:00Z,E-COMMERCE,DEBIT,50,FAILED`
+	ctx := context.Background()
+	s := NewTransactionService(memory.NewMemoryRepository(), WithIssueTracking(memory.NewIssueRepository()))
+	params := domain.PaginationParams{Page: 1, Limit: 10}
+
+	_, err := s.ProcessUpload(ctx, strings.NewReader(csvData), domain.UploadOptions{})
+	assert.NoError(t, err)
+	issues, err := s.GetIssues(ctx, domain.IssueFilter{}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(issues.Transactions))
+	id := issues.Transactions[0].ID
+
+	_, err = s.UpdateIssue(ctx, id, domain.IssueResolve, domain.IssueUpdate{Actor: "ops"})
+	assert.NoError(t, err)
+
+	for _, policy := range []domain.DuplicatePolicy{domain.DuplicateFlag, domain.DuplicateSkip, domain.DuplicateKeep} {
+		_, err = s.ProcessUpload(ctx, strings.NewReader(csvData), domain.UploadOptions{Duplicates: policy})
+		assert.NoError(t, err)
+		issues, err = s.GetIssues(ctx, domain.IssueFilter{}, params)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(issues.Transactions), "the same row uploaded again with %s stays resolved", policy)
+	}
+
+	// A flagged copy kept on review shares the issue too.
+	duplicates, err := s.ListDuplicates(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(duplicates))
+	kept, err := s.ResolveDuplicate(ctx, duplicates[0].Duplicate.ID, domain.DuplicateKeepBoth)
+	assert.NoError(t, err)
+	issues, err = s.GetIssues(ctx, domain.IssueFilter{}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(issues.Transactions))
+	record, err := s.GetIssue(ctx, kept.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, id, record.ID)
+	assert.Equal(t, domain.IssueResolved, record.State)
+
+	// Concurrent actions on the issue all end up in its history.
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.UpdateIssue(ctx, id, domain.IssueComment, domain.IssueUpdate{Actor: "ops", Comment: "Checked"})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	record, err = s.GetIssue(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.IssueResolved, record.State)
+	assert.Equal(t, 21, len(record.History))
+}
+
+func TestListTransactions_Filters(t *testing.T) {
+	wib := time.FixedZone("WIB", 7*60*60)
+	data := []domain.Transaction{
+		{ID: "1", Timestamp: time.Date(2024, 6, 1, 1, 0, 0, 0, wib), Name: "COMPANY A", Description: "salary", Type: domain.TypeCredit, Amount: idr(1000), Status: domain.StatusSuccess, Source: &domain.TransactionSource{UploadID: "u1"}},
+		{ID: "2", Timestamp: time.Date(2024, 6, 2, 12, 0, 0, 0, wib), Name: "RESTAURANT", Description: "dinner", Type: domain.TypeDebit, Amount: idr(100), Status: domain.StatusSuccess, Source: &domain.TransactionSource{UploadID: "u1"}},
+		{ID: "3", Timestamp: time.Date(2024, 6, 3, 12, 0, 0, 0, wib), Name: "E-COMMERCE", Description: "shoes", Type: domain.TypeDebit, Amount: idr(50), Status: domain.StatusFailed, Source: &domain.TransactionSource{UploadID: "u2"}},
+		{ID: "4", Timestamp: time.Date(2024, 6, 4, 12, 0, 0, 0, wib), Name: "TRANSFER", Description: "from company", Type: domain.TypeCredit, Amount: domain.NewMoney(20000, "USD"), Status: domain.StatusPending},
+	}
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(data, nil)
+
+	s := NewTransactionService(mockRepo)
+	params := domain.PaginationParams{Page: 1, Limit: 10, SortBy: "timestamp", SortDir: "asc"}
+
+	ids := func(filter domain.TransactionFilter) []string {
+		result, err := s.ListTransactions(context.Background(), filter, params)
+		assert.NoError(t, err)
+		ids := make([]string, 0, len(result.Transactions))
+		for _, tx := range result.Transactions {
+			ids = append(ids, tx.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"1", "2", "3", "4"}, ids(domain.TransactionFilter{}))
+	assert.Equal(t, []string{"1", "2"}, ids(domain.TransactionFilter{Statuses: []domain.TransactionStatus{domain.StatusSuccess}}))
+	assert.Equal(t, []string{"3", "4"}, ids(domain.TransactionFilter{Statuses: []domain.TransactionStatus{domain.StatusFailed, domain.StatusPending}}))
+	assert.Equal(t, []string{"2", "3"}, ids(domain.TransactionFilter{Type: domain.TypeDebit}))
+	assert.Equal(t, []string{"1", "2"}, ids(domain.TransactionFilter{FromDate: "2024-06-01", ToDate: "2024-06-02"}))
+	assert.Equal(t, []string{"2", "3", "4"}, ids(domain.TransactionFilter{From: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}))
+	assert.Equal(t, []string{"2", "4"}, ids(domain.TransactionFilter{MinAmount: &domain.Money{Minor: 100000, Exponent: 3}, MaxAmount: &domain.Money{Minor: 200000, Exponent: 3}}))
+	assert.Equal(t, []string{"1", "4"}, ids(domain.TransactionFilter{Query: "company"}))
+	assert.Equal(t, []string{"3"}, ids(domain.TransactionFilter{UploadID: "u2"}))
+	assert.Equal(t, []string{"4"}, ids(domain.TransactionFilter{Currency: "USD"}))
+}
+
 func TestProcessUpload_Success(t *testing.T) {
 
 	csvData := `1624507883, JOHN DOE, DEBIT, 25000, SUCCESS, restaurant`
 	reader := strings.NewReader(csvData)
 
 	mockRepo := new(MockTransactionRepository)
-	mockRepo.On("Store", mock.Anything, mock.AnythingOfType("[]domain.Transaction")).Return(nil)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.AnythingOfType("[]domain.Transaction")).Return(nil)
 
 	s := NewTransactionService(mockRepo)
 
-	err := s.ProcessUpload(context.Background(), reader)
+	result, err := s.ProcessUpload(context.Background(), reader, domain.UploadOptions{})
 
 	assert.NoError(t, err)
-	mockRepo.AssertCalled(t, "Store", mock.Anything, mock.AnythingOfType("[]domain.Transaction"))
+	assert.Equal(t, domain.ModeStrict, result.Mode)
+	assert.Equal(t, 1, result.Files[0].Report.AcceptedRows)
+	mockRepo.AssertExpectations(t)
+	writer.AssertExpectations(t)
 }
 
 func TestProcessUpload_ParseError(t *testing.T) {
@@ -120,15 +681,733 @@ func TestProcessUpload_ParseError(t *testing.T) {
 	reader := strings.NewReader(csvData)
 
 	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
 
 	s := NewTransactionService(mockRepo)
 
-	err := s.ProcessUpload(context.Background(), reader)
+	_, err := s.ProcessUpload(context.Background(), reader, domain.UploadOptions{})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid format")
 
-	mockRepo.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+	writer.AssertNotCalled(t, "Commit", mock.Anything)
+	writer.AssertCalled(t, "Rollback", mock.Anything)
+}
+
+func TestProcessUpload_LenientKeepsValidRows(t *testing.T) {
+	csvData := `1624507883, JOHN DOE, DEBIT, 25000, SUCCESS, restaurant
+1624507884, JANE DOE, REFUND, 1000, SUCCESS, unknown type
+1624507885, COMPANY A, CREDIT, 50000, SUCCESS, salary`
+	reader := strings.NewReader(csvData)
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.MatchedBy(func(txs []domain.Transaction) bool {
+		return len(txs) == 2
+	})).Return(nil)
+
+	s := NewTransactionService(mockRepo)
+
+	result, err := s.ProcessUpload(context.Background(), reader, domain.UploadOptions{Mode: domain.ModeLenient})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ModeLenient, result.Mode)
+	assert.Equal(t, 3, result.Files[0].Report.TotalRows)
+	assert.Equal(t, 2, result.Files[0].Report.AcceptedRows)
+	assert.Equal(t, 1, result.Files[0].Report.RejectedRows)
+	assert.Equal(t, "type", result.Files[0].Report.Errors[0].Field)
+	mockRepo.AssertExpectations(t)
+	writer.AssertExpectations(t)
+}
+
+func TestProcessUpload_DetectsOFX(t *testing.T) {
+	ofxData := `OFXHEADER:100
+<OFX><BANKTRANLIST>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240624<TRNAMT>1500<FITID>A1<NAME>COMPANY A</STMTTRN>
+</BANKTRANLIST></OFX>`
+	reader := strings.NewReader(ofxData)
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.MatchedBy(func(txs []domain.Transaction) bool {
+		return len(txs) == 1 && txs[0].Reference == "A1" && txs[0].Type == domain.TypeCredit
+	})).Return(nil)
+
+	s := NewTransactionService(mockRepo, WithParsers(newParsers()))
+
+	result, err := s.ProcessUpload(context.Background(), reader, domain.UploadOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.FormatOFX, result.Files[0].Format)
+	mockRepo.AssertExpectations(t)
+	writer.AssertExpectations(t)
+}
+
+func TestProcessUpload_DetectsMT940WithStatement(t *testing.T) {
+	mt940Data := `:20:STMT1
+:25:ACC-1
+:60F:C240601IDR1000,00
+:61:240610C500,00NTRFNONREF
+:86:COMPANY A
+:62F:C240630IDR1500,00
+-`
+	reader := strings.NewReader(mt940Data)
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.AnythingOfType("[]domain.Transaction")).Return(nil)
+
+	s := NewTransactionService(mockRepo, WithParsers(newParsers()))
+
+	result, err := s.ProcessUpload(context.Background(), reader, domain.UploadOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.FormatMT940, result.Files[0].Format)
+	assert.Equal(t, 1, len(result.Files[0].Statements))
+	assert.True(t, *result.Files[0].Statements[0].BalanceMatches)
+	mockRepo.AssertExpectations(t)
+	writer.AssertExpectations(t)
+}
+
+func TestProcessUpload_SniffsEncodingAndDelimiter(t *testing.T) {
+	csvData := "\xEF\xBB\xBFtimestamp;name;type;amount;status;description\n1624507883;JOHN DOE;DEBIT;25000;SUCCESS;caf\xC3\xA9\n"
+	reader := strings.NewReader(csvData)
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.MatchedBy(func(txs []domain.Transaction) bool {
+		return len(txs) == 1 && txs[0].Description == "café"
+	})).Return(nil)
+
+	s := NewTransactionService(mockRepo)
+
+	result, err := s.ProcessUpload(context.Background(), reader, domain.UploadOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DetectedSettings{Encoding: "utf-8", BOM: true, Delimiter: ";"}, result.Files[0].Detected)
+	mockRepo.AssertExpectations(t)
+	writer.AssertExpectations(t)
+}
+
+func TestProcessUpload_ForcedFormat(t *testing.T) {
+	// Without the forced format this headerless file would be sniffed as MT940.
+	data := `:20:STMT1
+:25:ACC-1
+`
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	s := NewTransactionService(mockRepo, WithParsers(newParsers()))
+
+	_, err := s.ProcessUpload(context.Background(), strings.NewReader(data), domain.UploadOptions{Format: domain.FormatCSV})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid format on line 0")
+	writer.AssertNotCalled(t, "Commit", mock.Anything)
+	writer.AssertCalled(t, "Rollback", mock.Anything)
+}
+
+func TestProcessUpload_UnknownFormat(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	s := NewTransactionService(mockRepo, WithParsers(newParsers()))
+
+	_, err := s.ProcessUpload(context.Background(), strings.NewReader(""), domain.UploadOptions{Format: "qif"})
+
+	assert.EqualError(t, err, `unsupported format "qif", expected one of: ofx, camt053, mt940, csv`)
+}
+
+func TestProcessUpload_RoutesByExtension(t *testing.T) {
+	// MT940 files do not always start with :20: on the first sniffed lines,
+	// e.g. when a long SWIFT header precedes the text block.
+	data := strings.Repeat("{1:F01BANKIDJAAXXX0000000000}\n", 200) + `{4:
+:20:STMT1
+:25:ACC-1
+:61:240610C500,00NTRFNONREF
+-}`
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.AnythingOfType("[]domain.Transaction")).Return(nil)
+
+	s := NewTransactionService(mockRepo, WithParsers(newParsers()))
+
+	result, err := s.ProcessUpload(context.Background(), strings.NewReader(data), domain.UploadOptions{Filename: "june.sta"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.FormatMT940, result.Files[0].Format)
+	assert.Equal(t, 1, result.Files[0].Report.AcceptedRows)
+}
+
+func TestProcessUpload_WritesFixedSizeBatches(t *testing.T) {
+	var csvData strings.Builder
+	for i := 0; i < 5; i++ {
+		csvData.WriteString("1624507883, JOHN DOE, DEBIT, 25000, SUCCESS, restaurant\n")
+	}
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.MatchedBy(func(txs []domain.Transaction) bool {
+		return len(txs) == 2
+	})).Return(nil).Twice()
+	writer.On("Write", mock.Anything, mock.MatchedBy(func(txs []domain.Transaction) bool {
+		return len(txs) == 1
+	})).Return(nil).Once()
+
+	s := NewTransactionService(mockRepo, WithBatchSize(2))
+
+	_, err := s.ProcessUpload(context.Background(), strings.NewReader(csvData.String()), domain.UploadOptions{})
+
+	assert.NoError(t, err)
+	writer.AssertExpectations(t)
+}
+
+func TestProcessUpload_StopsWhenContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		cancel()
+	}).Return(nil).Once()
+
+	s := NewTransactionService(mockRepo, WithBatchSize(1))
+
+	csvData := "1624507883, JOHN DOE, DEBIT, 25000, SUCCESS, restaurant\n1624507884, JOHN DOE, DEBIT, 25000, SUCCESS, restaurant\n"
+	_, err := s.ProcessUpload(ctx, strings.NewReader(csvData), domain.UploadOptions{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	writer.AssertNumberOfCalls(t, "Write", 1)
+	writer.AssertNotCalled(t, "Commit", mock.Anything)
+	writer.AssertCalled(t, "Rollback", mock.Anything)
+}
+
+func TestProcessUpload_ValidationRules(t *testing.T) {
+	validator, err := validation.New(validation.Config{Rules: []validation.RuleConfig{
+		{Rule: validation.RuleNonNegativeAmount, Action: validation.ActionReject},
+		{Rule: validation.RuleRequiredName, Action: validation.ActionWarn},
+		{Rule: validation.RuleMaxAmount, Action: validation.ActionTag, Tag: "large", Max: "1000000"},
+	}})
+	assert.NoError(t, err)
+
+	csvData := `date,name,amount,status
+1624507883,JOHN DOE,-25000,SUCCESS
+1624507884,,1000,SUCCESS
+1624507885,COMPANY A,5000000,SUCCESS`
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.MatchedBy(func(txs []domain.Transaction) bool {
+		return len(txs) == 3 && txs[2].Tags[0] == "large"
+	})).Return(nil)
+
+	s := NewTransactionService(mockRepo, WithValidator(validator))
+
+	result, err := s.ProcessUpload(context.Background(), strings.NewReader(csvData), domain.UploadOptions{Mode: domain.ModeLenient})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Files[0].Report.RejectedRows)
+	assert.Equal(t, []domain.RowError{{Line: 2, Field: "name", Reason: "name is empty", Rule: "required_name"}}, result.Files[0].Report.Warnings)
+	writer.AssertExpectations(t)
+}
+
+func TestProcessUpload_ValidationRejectsRow(t *testing.T) {
+	validator, err := validation.New(validation.Config{Rules: []validation.RuleConfig{
+		{Rule: validation.RuleNonZeroAmount, Action: validation.ActionReject},
+	}})
+	assert.NoError(t, err)
+
+	csvData := `1624507883, JOHN DOE, DEBIT, 0, SUCCESS, restaurant
+1624507884, JOHN DOE, DEBIT, 100, SUCCESS, restaurant`
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.MatchedBy(func(txs []domain.Transaction) bool {
+		return len(txs) == 1
+	})).Return(nil)
+
+	s := NewTransactionService(mockRepo, WithValidator(validator))
+
+	_, err = s.ProcessUpload(context.Background(), strings.NewReader(csvData), domain.UploadOptions{})
+	assert.EqualError(t, err, "invalid amount on line 0: amount is zero")
+	writer.AssertNotCalled(t, "Commit", mock.Anything)
+
+	result, err := s.ProcessUpload(context.Background(), strings.NewReader(csvData), domain.UploadOptions{Mode: domain.ModeLenient})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.RowError{{Line: 0, Field: "amount", Reason: "amount is zero", Rule: "non_zero_amount"}}, result.Files[0].Report.Errors)
+}
+
+func TestProcessUpload_AssignsStableIDs(t *testing.T) {
+	csvData := `1624507883, JOHN DOE, DEBIT, 25000, SUCCESS, coffee
+1624507883, JOHN DOE, DEBIT, 25000, SUCCESS, coffee
+1624512883, COMPANY A, CREDIT, 50000, PENDING, salary`
+
+	var uploads [][]domain.Transaction
+	for range 2 {
+		mockRepo := new(MockTransactionRepository)
+		writer := expectImport(mockRepo)
+		writer.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			uploads = append(uploads, args.Get(1).([]domain.Transaction))
+		}).Return(nil)
+
+		s := NewTransactionService(mockRepo)
+		result, err := s.ProcessUpload(context.Background(), strings.NewReader(csvData), domain.UploadOptions{Filename: "june.csv"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, result.UploadID)
+		assert.Equal(t, result.UploadID, uploads[len(uploads)-1][0].Source.UploadID)
+	}
+
+	first, second := uploads[0], uploads[1]
+	assert.Equal(t, 3, len(first))
+	for i := range first {
+		assert.Equal(t, first[i].ID, second[i].ID)
+	}
+	assert.Equal(t, first[0].ID+"-2", first[1].ID)
+	assert.NotEqual(t, first[0].ID, first[2].ID)
+	assert.NotEqual(t, first[0].Source.UploadID, second[0].Source.UploadID)
+	assert.Equal(t, domain.TransactionSource{UploadID: first[2].Source.UploadID, File: "june.csv", Line: 2}, *first[2].Source)
+}
+
+func TestProcessUpload_Account(t *testing.T) {
+	csvData := `timestamp,name,type,amount,status
+1624507883,JOHN DOE,DEBIT,25,SUCCESS`
+
+	mockRepo := new(MockTransactionRepository)
+	expectNoStoredTransactions(mockRepo)
+	writer := new(MockTransactionWriter)
+	mockRepo.On("Begin", mock.Anything, mock.MatchedBy(func(u domain.Upload) bool {
+		return u.AccountID == "usd-savings"
+	})).Return(writer, nil)
+	writer.On("Write", mock.Anything, mock.MatchedBy(func(txs []domain.Transaction) bool {
+		return txs[0].AccountID == "usd-savings" && txs[0].Amount == domain.NewMoney(2500, "USD")
+	})).Return(nil)
+	writer.On("Commit", mock.Anything).Return(&domain.Upload{Version: 1}, nil)
+	accounts := new(MockAccountRepository)
+	accounts.On("Get", mock.Anything, "usd-savings").Return(&domain.Account{ID: "usd-savings", Currency: "USD"}, nil)
+	accounts.On("Get", mock.Anything, domain.DefaultAccountID).Return(nil, domain.ErrNotFound)
+
+	s := NewTransactionService(mockRepo, WithAccounts(accounts))
+
+	result, err := s.ProcessUpload(context.Background(), strings.NewReader(csvData), domain.UploadOptions{AccountID: "usd-savings"})
+	assert.NoError(t, err)
+	assert.Equal(t, "usd-savings", result.AccountID)
+	writer.AssertExpectations(t)
+
+	_, err = s.ProcessUpload(context.Background(), strings.NewReader(csvData), domain.UploadOptions{})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.ErrorContains(t, err, `unknown account "default"`)
+}
+
+func TestProcessUpload_StoresVersionedUpload(t *testing.T) {
+	csvData := `1624507883, JOHN DOE, DEBIT, 25000, SUCCESS, restaurant`
+
+	mockRepo := new(MockTransactionRepository)
+	expectNoStoredTransactions(mockRepo)
+	writer := new(MockTransactionWriter)
+	mockRepo.On("Begin", mock.Anything, mock.MatchedBy(func(u domain.Upload) bool {
+		return u.ID != "" && u.AccountID == domain.DefaultAccountID && u.Filename == "june.csv" && !u.CreatedAt.IsZero()
+	})).Return(writer, nil)
+	writer.On("Write", mock.Anything, mock.Anything).Return(nil)
+	writer.On("Commit", mock.Anything).Return(&domain.Upload{Version: 7, Rows: 1}, nil)
+
+	s := NewTransactionService(mockRepo)
+
+	result, err := s.ProcessUpload(context.Background(), strings.NewReader(csvData), domain.UploadOptions{Filename: "june.csv"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, result.Version)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListUploads_NewestFirstPerAccount(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("ListUploads", mock.Anything).Return([]domain.Upload{
+		{ID: "a", Version: 1, AccountID: "checking"},
+		{ID: "b", Version: 2, AccountID: "savings"},
+		{ID: "c", Version: 3, AccountID: "checking"},
+	}, nil)
+	mockRepo.On("DeleteUpload", mock.Anything, "missing").Return(domain.ErrNotFound)
+
+	s := NewTransactionService(mockRepo)
+
+	uploads, err := s.ListUploads(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "b", "a"}, []string{uploads[0].ID, uploads[1].ID, uploads[2].ID})
+
+	uploads, err = s.ListUploads(context.Background(), "checking")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(uploads))
+	assert.Equal(t, "c", uploads[0].ID)
+
+	assert.ErrorIs(t, s.DeleteUpload(context.Background(), "missing"), domain.ErrNotFound)
+}
+
+func TestProcessUpload_Duplicates(t *testing.T) {
+	csvData := `timestamp,name,type,amount,status,reference
+2024-06-01T10:00:00Z,COFFEE,DEBIT,25,SUCCESS,
+2024-06-02T10:00:00Z,SALARY,CREDIT,1000,SUCCESS,
+2024-06-03T10:00:00Z,RENT,DEBIT,500,SUCCESS,`
+
+	upload := func(policy domain.DuplicatePolicy, stored []domain.Transaction) (*domain.UploadResponse, []domain.Transaction) {
+		var written []domain.Transaction
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("GetAll", mock.Anything).Return(stored, nil)
+		writer := expectImport(mockRepo)
+		writer.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			written = append(written, args.Get(1).([]domain.Transaction)...)
+		}).Return(nil).Maybe()
+
+		s := NewTransactionService(mockRepo)
+		result, err := s.ProcessUpload(context.Background(), strings.NewReader(csvData), domain.UploadOptions{Duplicates: policy})
+		assert.NoError(t, err)
+		return result, written
+	}
+
+	_, first := upload(domain.DuplicateFlag, []domain.Transaction{})
+	assert.Equal(t, 3, len(first))
+
+	// The stored salary was booked a few hours earlier under a longer name
+	// and the rent belongs to another account.
+	stored := []domain.Transaction{first[0], first[1], first[2]}
+	stored[1].ID = "salary"
+	stored[1].Name = "SALARY JUNE"
+	stored[1].Timestamp = stored[1].Timestamp.Add(-6 * time.Hour)
+	stored[2].AccountID = "savings"
+
+	result, written := upload(domain.DuplicateFlag, stored)
+	assert.Equal(t, domain.DuplicateSummary{Policy: domain.DuplicateFlag, Flagged: 2}, result.Duplicates)
+	assert.Equal(t, 3, len(written))
+	assert.Equal(t, &domain.DuplicateMatch{Of: first[0].ID, Rule: "id"}, written[0].Duplicate)
+	assert.NotEqual(t, first[0].ID, written[0].ID)
+	assert.Equal(t, &domain.DuplicateMatch{Of: "salary", Rule: "fuzzy"}, written[1].Duplicate)
+	assert.Nil(t, written[2].Duplicate)
+
+	result, written = upload(domain.DuplicateSkip, stored)
+	assert.Equal(t, domain.DuplicateSummary{Policy: domain.DuplicateSkip, Skipped: 2}, result.Duplicates)
+	assert.Equal(t, 1, len(written))
+	assert.Equal(t, "RENT", written[0].Name)
+
+	result, written = upload(domain.DuplicateKeep, stored)
+	assert.Equal(t, domain.DuplicateSummary{Policy: domain.DuplicateKeep}, result.Duplicates)
+	assert.Equal(t, 3, len(written))
+	assert.Nil(t, written[0].Duplicate)
+	// The kept copy is stored next to the original under its own ID.
+	assert.Equal(t, first[0].ID+"-dup-"+result.UploadID, written[0].ID)
+	assert.Equal(t, first[1].ID, written[1].ID, "only a matching ID is renamed")
+}
+
+type slowRepository struct {
+	domain.TransactionRepository
+}
+
+func (r slowRepository) GetAll(ctx context.Context) ([]domain.Transaction, error) {
+	data, err := r.TransactionRepository.GetAll(ctx)
+	time.Sleep(20 * time.Millisecond)
+	return data, err
+}
+
+func TestProcessUpload_ConcurrentOverlappingUploads(t *testing.T) {
+	csvData := `timestamp,name,type,amount,status
+2024-06-01T10:00:00Z,COFFEE,DEBIT,25,SUCCESS
+2024-06-02T10:00:00Z,SALARY,CREDIT,1000,SUCCESS`
+
+	// Every upload takes a while between loading the stored rows and
+	// committing, so unserialised uploads would all see an empty account.
+	repo := slowRepository{memory.NewMemoryRepository()}
+	s := NewTransactionService(repo, WithBatchSize(1))
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.ProcessUpload(context.Background(), strings.NewReader(csvData), domain.UploadOptions{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// Whichever upload came first holds the originals; the others are
+	// flagged against them.
+	data, _ := repo.GetAll(context.Background())
+	assert.Equal(t, 8, len(data))
+	ids := make(map[string]bool)
+	unflagged := 0
+	for _, tx := range data {
+		ids[tx.ID] = true
+		if tx.Duplicate == nil {
+			unflagged++
+		}
+	}
+	assert.Equal(t, 2, unflagged)
+	assert.Equal(t, 8, len(ids))
+}
+
+func TestProcessUpload_SettlesPending(t *testing.T) {
+	june := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	pending := domain.Transaction{
+		ID: "card", AccountID: domain.DefaultAccountID, Timestamp: june, Name: "HOTEL", Type: domain.TypeDebit,
+		Amount: idr(750), Status: domain.StatusPending,
+		StatusHistory: []domain.StatusChange{{Status: domain.StatusPending, At: june, UploadID: "june"}},
+	}
+	csvData := `timestamp,name,type,amount,status,description
+2024-06-03T08:00:00Z,HOTEL,DEBIT,750,SUCCESS,Check-out
+2024-06-03T09:00:00Z,COFFEE,DEBIT,25,SUCCESS,`
+
+	var written []domain.Transaction
+	var settled domain.Transaction
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return([]domain.Transaction{pending}, nil)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		written = append(written, args.Get(1).([]domain.Transaction)...)
+	}).Return(nil)
+	writer.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		settled = args.Get(1).(domain.Transaction)
+	}).Return(nil).Once()
+
+	s := NewTransactionService(mockRepo)
+	result, err := s.ProcessUpload(context.Background(), strings.NewReader(csvData), domain.UploadOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Reconciled)
+	assert.Equal(t, domain.DuplicateSummary{Policy: domain.DuplicateFlag}, result.Duplicates)
+
+	assert.Equal(t, 1, len(written))
+	assert.Equal(t, "COFFEE", written[0].Name)
+	assert.Equal(t, 1, len(written[0].StatusHistory))
+
+	assert.Equal(t, "card", settled.ID)
+	assert.Equal(t, domain.StatusSuccess, settled.Status)
+	assert.Equal(t, "Check-out", settled.Description)
+	assert.Equal(t, 2, len(settled.StatusHistory))
+	assert.Equal(t, domain.StatusPending, settled.StatusHistory[0].Status)
+	assert.Equal(t, domain.StatusChange{
+		Status: domain.StatusSuccess, At: settled.StatusHistory[1].At, UploadID: result.UploadID,
+		TransactionID: settled.StatusHistory[1].TransactionID, Timestamp: time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC),
+	}, settled.StatusHistory[1])
+	assert.NotEqual(t, "", settled.StatusHistory[1].TransactionID, "the settling row is remembered")
+	assert.False(t, settled.StatusHistory[1].At.IsZero())
+	writer.AssertExpectations(t)
+}
+
+func TestProcessUpload_SettlingStatementUploadedTwice(t *testing.T) {
+	ctx := context.Background()
+	s := NewTransactionService(memory.NewMemoryRepository())
+	settling := `timestamp,name,type,amount,status
+2024-06-04T08:00:00Z,HOTEL,DEBIT,50,SUCCESS`
+
+	_, err := s.ProcessUpload(ctx, strings.NewReader(`timestamp,name,type,amount,status
+2024-06-01T10:00:00Z,HOTEL,DEBIT,50,PENDING`), domain.UploadOptions{})
+	assert.NoError(t, err)
+	first, err := s.ProcessUpload(ctx, strings.NewReader(settling), domain.UploadOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, first.Reconciled)
+
+	// Three days after the pending row the settling row is outside the
+	// fuzzy window of the stored one, but is still known by its own ID.
+	again, err := s.ProcessUpload(ctx, strings.NewReader(settling), domain.UploadOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, again.Reconciled)
+	assert.Equal(t, 1, again.Duplicates.Flagged)
+
+	balance, err := s.GetBalance(ctx, "", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "-50.00", balance.Balances[0].Balance.String())
+}
+
+func TestDeleteUpload_UndoesSettlement(t *testing.T) {
+	ctx := context.Background()
+	s := NewTransactionService(memory.NewMemoryRepository())
+
+	june, err := s.ProcessUpload(ctx, strings.NewReader(`timestamp,name,type,amount,status
+2024-06-01T10:00:00Z,HOTEL,DEBIT,750,PENDING`), domain.UploadOptions{})
+	assert.NoError(t, err)
+	july, err := s.ProcessUpload(ctx, strings.NewReader(`timestamp,name,type,amount,status
+2024-06-03T08:00:00Z,HOTEL,DEBIT,750,SUCCESS`), domain.UploadOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, july.Reconciled)
+
+	uploads, err := s.ListUploads(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{july.UploadID, june.UploadID}, []string{uploads[0].ID, uploads[1].ID})
+	assert.Equal(t, 1, uploads[0].Rows, "the settlement is a row of the upload that brought it")
+
+	all, _ := s.repo.GetAll(ctx)
+	assert.Equal(t, 1, len(all))
+	id := all[0].ID
+
+	assert.NoError(t, s.DeleteUpload(ctx, july.UploadID))
+	tx, err := s.GetTransaction(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusPending, tx.Status)
+	assert.Equal(t, []domain.StatusChange{{Status: domain.StatusPending, At: tx.StatusHistory[0].At, UploadID: june.UploadID}}, tx.StatusHistory)
+
+	// With the settlement gone the row can be settled again.
+	again, err := s.ProcessUpload(ctx, strings.NewReader(`timestamp,name,type,amount,status
+2024-06-03T08:00:00Z,HOTEL,DEBIT,750,FAILED`), domain.UploadOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, again.Reconciled)
+	tx, _ = s.GetTransaction(ctx, id)
+	assert.Equal(t, domain.StatusFailed, tx.Status)
+}
+
+func TestGetBalance_IgnoresFlaggedDuplicates(t *testing.T) {
+	data := []domain.Transaction{
+		{ID: "a", AccountID: "default", Type: domain.TypeCredit, Amount: idr(1000), Status: domain.StatusSuccess},
+		{ID: "b", AccountID: "default", Type: domain.TypeCredit, Amount: idr(1000), Status: domain.StatusSuccess, Duplicate: &domain.DuplicateMatch{Of: "a", Rule: "fuzzy"}},
+	}
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return(data, nil)
+
+	s := NewTransactionService(mockRepo)
+
+	balance, err := s.GetBalance(context.Background(), "", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, idr(1000), balance.Balances[0].Balance)
+}
+
+func TestDuplicates_Review(t *testing.T) {
+	original := domain.Transaction{ID: "a", Name: "SALARY", Metadata: map[string]string{"branch": "01"}}
+	duplicate := domain.Transaction{ID: "b", Name: "SALARY", Reference: "FT1", Metadata: map[string]string{"branch": "02", "channel": "atm"}, Duplicate: &domain.DuplicateMatch{Of: "a", Rule: "fuzzy"}}
+
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetAll", mock.Anything).Return([]domain.Transaction{original, duplicate}, nil)
+	mockRepo.On("GetByID", mock.Anything, "a").Return(&original, nil)
+	mockRepo.On("GetByID", mock.Anything, "b").Return(&duplicate, nil)
+
+	s := NewTransactionService(mockRepo)
+
+	reviews, err := s.ListDuplicates(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(reviews))
+	assert.Equal(t, "b", reviews[0].Duplicate.ID)
+	assert.Equal(t, "a", reviews[0].Original.ID)
+
+	_, err = s.ResolveDuplicate(context.Background(), "a", domain.DuplicateMerge)
+	assert.ErrorIs(t, err, domain.ErrNotDuplicate)
+
+	merged := original
+	merged.Reference = "FT1"
+	merged.Metadata = map[string]string{"branch": "01", "channel": "atm"}
+	mockRepo.On("UpdateTransaction", mock.Anything, merged).Return(nil).Once()
+	mockRepo.On("DeleteTransaction", mock.Anything, "b").Return(nil).Once()
+
+	tx, err := s.ResolveDuplicate(context.Background(), "b", domain.DuplicateMerge)
+	assert.NoError(t, err)
+	assert.Equal(t, "FT1", tx.Reference)
+
+	kept := duplicate
+	kept.Duplicate = nil
+	mockRepo.On("UpdateTransaction", mock.Anything, kept).Return(nil).Once()
+
+	tx, err = s.ResolveDuplicate(context.Background(), "b", domain.DuplicateKeepBoth)
+	assert.NoError(t, err)
+	assert.Nil(t, tx.Duplicate)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetTransaction(t *testing.T) {
+	mockRepo := new(MockTransactionRepository)
+	mockRepo.On("GetByID", mock.Anything, "abc").Return(&domain.Transaction{ID: "abc", Name: "JOHN DOE"}, nil)
+	mockRepo.On("GetByID", mock.Anything, "missing").Return(nil, domain.ErrNotFound)
+
+	s := NewTransactionService(mockRepo)
+
+	tx, err := s.GetTransaction(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "JOHN DOE", tx.Name)
+
+	_, err = s.GetTransaction(context.Background(), "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func gzipData(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func zipData(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"january.csv", "february.csv", "statement.sta"} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestProcessUpload_Gzip(t *testing.T) {
+	data := gzipData(t, "1624507883, JOHN DOE, DEBIT, 25000, SUCCESS, restaurant\n")
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.MatchedBy(func(txs []domain.Transaction) bool {
+		return len(txs) == 1
+	})).Return(nil)
+
+	s := NewTransactionService(mockRepo)
+
+	result, err := s.ProcessUpload(context.Background(), bytes.NewReader(data), domain.UploadOptions{Filename: "june.csv.gz"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "gzip", result.Archive)
+	assert.Equal(t, "june.csv", result.Files[0].Name)
+	mockRepo.AssertExpectations(t)
+	writer.AssertExpectations(t)
+}
+
+func TestProcessUpload_ZipStoresAllFilesTogether(t *testing.T) {
+	data := zipData(t, map[string]string{
+		"january.csv":  "1624507883, JOHN DOE, DEBIT, 25000, SUCCESS, restaurant\n",
+		"february.csv": "1624512883, COMPANY A, CREDIT, 50000, SUCCESS, salary\n",
+		"statement.sta": `:20:STMT1
+:25:ACC-1
+:61:240610C500,00NTRFNONREF
+-`,
+	})
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	writer.On("Write", mock.Anything, mock.MatchedBy(func(txs []domain.Transaction) bool {
+		return len(txs) == 3
+	})).Return(nil).Once()
+
+	s := NewTransactionService(mockRepo, WithParsers(newParsers()))
+
+	result, err := s.ProcessUpload(context.Background(), bytes.NewReader(data), domain.UploadOptions{Filename: "statements.zip"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "zip", result.Archive)
+	assert.Equal(t, 3, len(result.Files))
+	assert.Equal(t, domain.FormatMT940, result.Files[2].Format)
+	mockRepo.AssertExpectations(t)
+	writer.AssertExpectations(t)
+}
+
+func TestProcessUpload_ZipWithBadFileStoresNothing(t *testing.T) {
+	data := zipData(t, map[string]string{
+		"january.csv":  "1624507883, JOHN DOE, DEBIT, 25000, SUCCESS, restaurant\n",
+		"february.csv": "1624512883, COMPANY A, CREDIT\n",
+	})
+
+	mockRepo := new(MockTransactionRepository)
+	writer := expectImport(mockRepo)
+	s := NewTransactionService(mockRepo)
+
+	_, err := s.ProcessUpload(context.Background(), bytes.NewReader(data), domain.UploadOptions{})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "february.csv: invalid format")
+	writer.AssertNotCalled(t, "Commit", mock.Anything)
+	writer.AssertCalled(t, "Rollback", mock.Anything)
 }
 
 func generateMockData(rows int) []domain.Transaction {
@@ -145,7 +1424,7 @@ func generateMockData(rows int) []domain.Transaction {
 			Timestamp: time.Now().Add(time.Duration(i) * time.Second),
 			Name:      "E-COMMERCE " + strconv.Itoa(i),
 			Type:      domain.TypeDebit,
-			Amount:    int64(i * 100), // Buat amount berbeda agar sorting bekerja
+			Amount:    idr(int64(i * 100)), // Buat amount berbeda agar sorting bekerja
 			Status:    status,
 		})
 	}
@@ -172,7 +1451,7 @@ func BenchmarkGetIssues(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		_, err := s.GetIssues(ctx, params)
+		_, err := s.GetIssues(ctx, domain.IssueFilter{}, params)
 		if err != nil {
 			b.Fatal(err)
 		}
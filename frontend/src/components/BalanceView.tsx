@@ -1,7 +1,7 @@
 
 "use client";
 import React from "react";
-import { BalanceData } from "@/types/api.types";
+import { BalanceData, Money } from "@/types/api.types";
 
 interface Props {
   data: BalanceData | undefined;
@@ -9,13 +9,13 @@ interface Props {
   error: Error | null;
 }
 
-const formatCurrency = (amount: number) => {
+const formatCurrency = (money: Money) => {
   return new Intl.NumberFormat("id-ID", {
     style: "currency",
-    currency: "IDR",
+    currency: money.currency,
     minimumFractionDigits: 0,
-    maximumFractionDigits: 0,
-  }).format(amount);
+    maximumFractionDigits: money.exponent,
+  }).format(Number(money.value));
 };
 
 
@@ -26,13 +26,17 @@ export function BalanceView({ data, isLoading, error }: Props) {
       <p className="component-description">Latest reconciled closing balance for the selected statement period.</p>
       {isLoading && <p className="loading-text">Loading balance...</p>}
       {error && <p className="error-text">Error: {error.message}</p>}
-      {data && (
-        <div 
-          className={`balance-view ${data.total_balance < 0 ? 'negative' : ''}`}
+      {data && data.balances.length === 0 && (
+        <div className="balance-view">{formatCurrency({ minor: 0, currency: "IDR", exponent: 2, value: "0" })}</div>
+      )}
+      {data && data.balances.map((b) => (
+        <div
+          key={b.currency}
+          className={`balance-view ${b.balance.minor < 0 ? 'negative' : ''}`}
         >
-          {formatCurrency(data.total_balance)}
+          {formatCurrency(b.balance)}
         </div>
-      )}
+      ))}
     </section>
   );
 }
\ No newline at end of file
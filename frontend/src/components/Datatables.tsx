@@ -2,7 +2,7 @@
 "use client";
 
 import React from "react";
-import { IssuesData, IssuesQueryParams, Transaction } from "@/types/api.types";
+import { IssuesData, IssuesQueryParams, Money, Transaction } from "@/types/api.types";
 
 interface Props {
   data: IssuesData | undefined;
@@ -28,13 +28,13 @@ export function Datatable({
   const formatDate = (dateString: string) => {
     return new Date(dateString).toLocaleString("id-ID");
   }
-  const formatCurrency = (amount: number) => {
+  const formatCurrency = (money: Money) => {
     return new Intl.NumberFormat("id-ID", {
       style: "currency",
-      currency: "IDR",
+      currency: money.currency,
       minimumFractionDigits: 0,
-      maximumFractionDigits: 0,
-    }).format(amount);
+      maximumFractionDigits: money.exponent,
+    }).format(Number(money.value));
   };
   const handleSort = (newSortBy: string) => {
     const isAsc = queryParams.sortBy === newSortBy && queryParams.sortDir === "asc";
@@ -59,7 +59,7 @@ export function FileUploader({ onUpload, isPending }: Props) {
         <input
           ref={fileInputRef}
           type="file"
-          accept=".csv"
+          accept=".csv,.ofx,.qfx,.xml,.sta,.mt940,.txt,.gz,.zip"
           onChange={handleFileChange}
           disabled={isPending}
           hidden
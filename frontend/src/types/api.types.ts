@@ -1,9 +1,16 @@
 
+export interface Money {
+  minor: number;
+  currency: string;
+  exponent: number;
+  value: string;
+}
+
 export interface Transaction {
   timestamp: string; 
   name: string;
   type: "DEBIT" | "CREDIT";
-  amount: number; 
+  amount: Money; 
   status: "SUCCESS" | "FAILED" | "PENDING";
   description: string;
 }
@@ -14,8 +21,13 @@ export interface APIResponse<T> {
   data?: T;
 }
 
+export interface CurrencyBalance {
+  currency: string;
+  balance: Money;
+}
+
 export interface BalanceData {
-  total_balance: number;
+  balances: CurrencyBalance[];
 }
 
 export interface PaginationMetadata {